@@ -0,0 +1,153 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/DataDrake/cli-ng/cmd"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"github.com/DataDrake/ypkg-update-checker/git"
+	"github.com/DataDrake/ypkg-update-checker/github"
+	"github.com/DataDrake/ypkg-update-checker/pkg"
+	"github.com/jmoiron/sqlx"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Bump updates a package.yml to the latest release found upstream
+var Bump = cmd.CMD{
+	Name:  "bump",
+	Alias: "b",
+	Short: "Update a package.yml to the latest release found by the last update",
+	Args:  &BumpArgs{},
+	Run:   BumpRun,
+}
+
+// BumpArgs contains the arguments for the "bump" subcommand
+type BumpArgs struct {
+	Package string `desc:"Name of the package"`
+}
+
+// BumpRun carries out rewriting the package.yml of a package, and committing it to a new branch with --commit
+func BumpRun(r *cmd.RootCMD, c *cmd.CMD) {
+	flags := r.Flags.(*GlobalFlags)
+	args := c.Args.(*BumpArgs)
+	rdb, err := db.Open()
+	if err != nil {
+		fmt.Printf("Failed to open database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	defer rdb.Close()
+	version, changelog, err := bumpPackage(rdb, args.Package)
+	if err != nil {
+		fmt.Printf("Failed to bump %s, reason: \"%s\"\n", args.Package, err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("Bumped %s to %s\n", args.Package, version)
+	if !flags.Commit {
+		return
+	}
+	if err = commitBump(args.Package, version, changelog); err != nil {
+		fmt.Printf("Failed to commit, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+// commitBump commits the rewritten package.yml of a package to a new topic branch
+func commitBump(name, version, changelog string) error {
+	branch := BumpBranch(name, version)
+	repo := git.Repo{Dir: filepath.Join(".", name)}
+	if err := repo.CreateBranch(branch); err != nil {
+		return err
+	}
+	if err := repo.Commit(BumpMessage(name, version, changelog), "package.yml"); err != nil {
+		return err
+	}
+	fmt.Printf("Committed %s to branch %s\n", name, branch)
+	return nil
+}
+
+// BumpBranch gets the name of the topic branch for an update
+func BumpBranch(name, version string) string {
+	return "update/" + name + "-" + version
+}
+
+// BumpMessage gets the standard commit message for an update, linking to the upstream changelog if known
+func BumpMessage(name, version, changelog string) string {
+	msg := name + ": Update to " + version
+	if changelog != "" {
+		msg += "\n\nChangelog: " + changelog
+	}
+	return msg
+}
+
+// bumpPackage rewrites the package.yml of a package for the newest release of its first source,
+// returning the new version and its upstream changelog, if known
+func bumpPackage(rdb *sqlx.DB, name string) (string, string, error) {
+	releases, err := db.GetReleases(rdb, name)
+	if err != nil {
+		return "", "", err
+	}
+	var latest *db.Release
+	for i, r := range releases {
+		if !r.Auxiliary && r.Status == db.StatusOutOfDate {
+			latest = &releases[i]
+			break
+		}
+	}
+	if latest == nil {
+		return "", "", errors.New("no newer release found, try running update first")
+	}
+	path := filepath.Join(".", name, "package.yml")
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", "", err
+	}
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	yml, err := pkg.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	if len(yml.Sources) == 0 {
+		return "", "", errors.New("package.yml has no sources")
+	}
+	old := yml.Sources[0]
+	next, err := old.Next(yml.Version, latest.Latest, latest.Source)
+	if err != nil {
+		return "", "", err
+	}
+	if next.Kind == pkg.SourceTarball {
+		fmt.Printf("Downloading %s...\n", next.URL)
+		if next.SHA256, err = pkg.Download(next.URL); err != nil {
+			return "", "", err
+		}
+	}
+	version := strings.TrimLeft(latest.Latest, "vV")
+	raw, err = pkg.Rewrite(raw, version, old, next)
+	if err != nil {
+		return "", "", err
+	}
+	// Only a release page is a changelog, not the tarball
+	changelog := github.ReleaseURL(old.URL, latest.Latest)
+	return version, changelog, ioutil.WriteFile(path, raw, info.Mode())
+}
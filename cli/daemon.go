@@ -0,0 +1,198 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/DataDrake/cli-ng/cmd"
+	"github.com/DataDrake/ypkg-update-checker/config"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"github.com/jmoiron/sqlx"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// Daemon periodically rechecks every package and regenerates the configured reports
+var Daemon = cmd.CMD{
+	Name:  "daemon",
+	Alias: "d",
+	Short: "Keep checking for updates and regenerating reports until stopped",
+	Args:  &DaemonArgs{},
+	Run:   DaemonRun,
+}
+
+// DaemonArgs contains the arguments for the "daemon" subcommand
+type DaemonArgs struct{}
+
+// DaemonRun carries out checking for updates, one run per interval
+func DaemonRun(r *cmd.RootCMD, c *cmd.CMD) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	if cfg.Daemon.Interval.Duration <= 0 {
+		fmt.Println("The daemon interval must be longer than zero")
+		os.Exit(1)
+	}
+	db.MaxAge = cfg.MaxAge.Duration
+	rdb, err := db.Open()
+	if err != nil {
+		fmt.Printf("Failed to open database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	defer rdb.Close()
+	checks, err := newChecker(rdb, ".", cfg)
+	if err != nil {
+		fmt.Printf("Failed to set up checks, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	for {
+		start := time.Now()
+		err = checks.daemonCycle(cfg, stop)
+		if err == errStopped {
+			checks.notifier.Close()
+			fmt.Println("Stopped.")
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Run failed, reason: \"%s\"\n", err.Error())
+		}
+		for _, report := range cfg.Daemon.Reports {
+			if err = saveReport(rdb, report); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to write report '%s', reason: \"%s\"\n", report.Path, err.Error())
+			}
+		}
+		select {
+		case <-time.After(cfg.Daemon.Interval.Duration - time.Since(start)):
+		case <-stop:
+			checks.notifier.Close()
+			fmt.Println("Stopped.")
+			return
+		}
+	}
+}
+
+// errStopped is returned by daemonCycle when the daemon is asked to stop part way through
+var errStopped = errors.New("stopped")
+
+// daemonCycle carries out a single run, spreading the lookups of stale packages across the interval
+func (c *checker) daemonCycle(cfg config.Config, stop chan os.Signal) error {
+	// Reloaded every time, to pick up a refreshed feed
+	if err := c.loadSecurity(cfg.Security); err != nil {
+		return err
+	}
+	run, err := db.NewRun(c.rdb, Version)
+	if err != nil {
+		return err
+	}
+	packages, err := c.scanPackages()
+	if err != nil {
+		return err
+	}
+	stale := make([]string, 0)
+	for _, p := range packages {
+		due, err := c.packageStale(p)
+		if err != nil {
+			return err
+		}
+		if due {
+			stale = append(stale, p)
+			continue
+		}
+		// Still cheap to pick up local changes to package.yml
+		if err = c.checkPackage(run, p, false); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to update %s, reason: %s\n", p, err.Error())
+		}
+	}
+	if len(stale) > 0 {
+		// Leave some of the interval for the reports to be generated
+		spacing := cfg.Daemon.Interval.Duration * 9 / 10 / time.Duration(len(stale))
+		for i, p := range stale {
+			if i > 0 {
+				select {
+				case <-time.After(spacing):
+				case <-stop:
+					run.Finish(c.rdb, len(packages))
+					return errStopped
+				}
+			}
+			if err = c.checkPackage(run, p, false); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to update %s, reason: %s\n", p, err.Error())
+			}
+		}
+	}
+	if err = rescorePackages(c.rdb, cfg.Weights); err != nil {
+		return err
+	}
+	if err = run.Finish(c.rdb, len(packages)); err != nil {
+		return err
+	}
+	fmt.Printf("Finished run %d, checked %d of %d packages\n", run.ID, len(stale), len(packages))
+	return nil
+}
+
+// packageStale checks if any source of a package needs to be looked up again
+func (c *checker) packageStale(p string) (bool, error) {
+	releases, err := db.GetReleases(c.rdb, p)
+	if err != nil || len(releases) == 0 {
+		return true, err
+	}
+	// Broken overrides are reported when the package is checked
+	monitoring, _ := c.openMonitoring(p)
+	for i := range releases {
+		for _, m := range monitoring {
+			if releases[i].Tracks(0, m.Series) {
+				releases[i].Monitoring = m
+			}
+		}
+	}
+	for _, r := range releases {
+		if r.Stale() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// saveReport regenerates a report, replacing the old one only once the new one is complete
+func saveReport(rdb *sqlx.DB, report config.Report) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(report.Path), ".report")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	err = writeReport(tmp, rdb, report.Format, report.Since, report.Sort, report.SecurityOnly)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), report.Path)
+}
@@ -0,0 +1,128 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"fmt"
+	"github.com/DataDrake/cli-ng/cmd"
+	"github.com/DataDrake/ypkg-update-checker/config"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"github.com/DataDrake/ypkg-update-checker/digest"
+	"github.com/DataDrake/ypkg-update-checker/pkg"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Digest emails each maintainer the packages that became out of date
+var Digest = cmd.CMD{
+	Name:  "digest",
+	Alias: "dg",
+	Short: "Email each maintainer the packages that became out of date in the last day",
+	Args:  &DigestArgs{},
+	Run:   DigestRun,
+}
+
+// DigestArgs contains the arguments for the "digest" subcommand
+type DigestArgs struct {
+	Destination string `desc:"'smtp' to send them, a file ending in '.mbox', or a directory for '.eml' files"`
+}
+
+// DigestRun carries out sending the digests, covering the last 24 hours unless --since is set
+func DigestRun(r *cmd.RootCMD, c *cmd.CMD) {
+	flags := r.Flags.(*GlobalFlags)
+	args := c.Args.(*DigestArgs)
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	from, err := mail.ParseAddress(cfg.Digest.From)
+	if err != nil {
+		fmt.Printf("Invalid sender for digests '%s', reason: \"%s\"\n", cfg.Digest.From, err.Error())
+		os.Exit(1)
+	}
+	maintainers, err := config.LoadMaintainers(cfg.Digest.Maintainers)
+	if err != nil {
+		fmt.Printf("Failed to load maintainers, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	rdb, err := db.Open()
+	if err != nil {
+		fmt.Printf("Failed to open database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	defer rdb.Close()
+	since := time.Now().Add(-24 * time.Hour)
+	if flags.Since != "" {
+		if since, err = sinceTime(rdb, flags.Since); err != nil {
+			fmt.Printf("Invalid value for since '%s', reason: \"%s\"\n", flags.Since, err.Error())
+			os.Exit(1)
+		}
+	}
+	releases, err := db.GetAllReleases(rdb)
+	if err != nil {
+		fmt.Printf("Failed to read database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	prev, err := db.GetHistoryAt(rdb, since)
+	if err != nil {
+		fmt.Printf("Failed to read database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	diff := pkg.NewDiff(since, prev, releases)
+	lookup := func(name string) *mail.Address {
+		return maintainerOf(name, maintainers, cfg.Digest.Fallback)
+	}
+	digests := digest.Group(since, diff.OutOfDate, lookup)
+	switch {
+	case args.Destination == "smtp":
+		err = digest.Send(digests, from, cfg.Digest.SMTP)
+	case strings.HasSuffix(args.Destination, ".mbox"):
+		err = digest.WriteMbox(digests, from, args.Destination)
+	default:
+		err = digest.WriteEML(digests, from, args.Destination)
+	}
+	if err != nil {
+		fmt.Printf("Failed to deliver digests, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("Delivered %d digests\n", len(digests))
+}
+
+// maintainerOf finds who to send the changes to a package, from the mapping file, package.yml or the fallback
+func maintainerOf(name string, maintainers map[string]string, fallback string) *mail.Address {
+	address := maintainers[name]
+	if address == "" {
+		yml, err := pkg.Open(filepath.Join(".", name, "package.yml"))
+		if err == nil && yml.Maintainer != nil && yml.Maintainer.Email != "" {
+			return &mail.Address{Name: yml.Maintainer.Name, Address: yml.Maintainer.Email}
+		}
+		address = fallback
+	}
+	if address == "" {
+		return nil
+	}
+	to, err := mail.ParseAddress(address)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid maintainer for %s '%s', skipping\n", name, address)
+		return nil
+	}
+	return to
+}
@@ -0,0 +1,56 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"fmt"
+	"github.com/DataDrake/cli-ng/cmd"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"github.com/DataDrake/ypkg-update-checker/pkg"
+	"os"
+)
+
+// History shows every recorded change to a single package
+var History = cmd.CMD{
+	Name:  "history",
+	Alias: "hi",
+	Short: "Show the timeline of upstream and packaged versions for a package",
+	Args:  &HistoryArgs{},
+	Run:   HistoryRun,
+}
+
+// HistoryArgs contains the arguments for the "history" subcommand
+type HistoryArgs struct {
+	Package string `desc:"Name of the package"`
+}
+
+// HistoryRun carries out printing the history of a package
+func HistoryRun(r *cmd.RootCMD, c *cmd.CMD) {
+	args := c.Args.(*HistoryArgs)
+	rdb, err := db.Open()
+	if err != nil {
+		fmt.Printf("Failed to open database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	defer rdb.Close()
+	snapshots, err := db.GetHistory(rdb, args.Package)
+	if err != nil {
+		fmt.Printf("Failed to read database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	pkg.NewHistory(args.Package, snapshots).Print()
+}
@@ -0,0 +1,143 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"fmt"
+	"github.com/DataDrake/cli-ng/cmd"
+	"github.com/DataDrake/ypkg-update-checker/config"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"github.com/DataDrake/ypkg-update-checker/pkg"
+	"os"
+	"regexp"
+)
+
+// Plan lists the pending updates of a group of packages, and can bump all of them
+var Plan = cmd.CMD{
+	Name:  "plan",
+	Alias: "p",
+	Short: "List the pending updates of a group of packages in dependency order",
+	Args:  &PlanArgs{},
+	Run:   PlanRun,
+}
+
+// PlanArgs contains the arguments for the "plan" subcommand
+type PlanArgs struct {
+	Group string `desc:"Name of a group in the config file"`
+}
+
+// PlanFormat is the format string for a single row of a plan
+const PlanFormat = "%5v  %-30s  %-15s  %-15s  %s\n"
+
+// PlanRun carries out listing the pending updates of a group, and bumping them with --bump
+func PlanRun(r *cmd.RootCMD, c *cmd.CMD) {
+	flags := r.Flags.(*GlobalFlags)
+	args := c.Args.(*PlanArgs)
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	group, err := cfg.FindGroup(args.Group)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	rdb, err := db.Open()
+	if err != nil {
+		fmt.Printf("Failed to open database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	defer rdb.Close()
+	releases, err := db.GetAllReleases(rdb)
+	if err != nil {
+		fmt.Printf("Failed to read database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	packages, err := db.GetAllPackages(rdb)
+	if err != nil {
+		fmt.Printf("Failed to read database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	pending, err := groupPending(group, releases, packages)
+	if err != nil {
+		fmt.Printf("Invalid match for group '%s', reason: \"%s\"\n", group.Name, err.Error())
+		os.Exit(1)
+	}
+	if len(pending) == 0 {
+		fmt.Printf("No pending updates for '%s'.\n", group.Name)
+		return
+	}
+	names := make([]string, 0)
+	for name := range pending {
+		names = append(names, name)
+	}
+	order := pkg.NewGraph(packages).Order(names)
+	fmt.Printf(PlanFormat, "Step", "Name", "Old Version", "New Version", "Location")
+	for i, name := range order {
+		release := pending[name]
+		fmt.Printf(PlanFormat, i+1, name, release.Current, release.Latest, release.Source)
+	}
+	if !flags.Bump {
+		return
+	}
+	for _, name := range order {
+		version, changelog, err := bumpPackage(rdb, name)
+		if err != nil {
+			fmt.Printf("Failed to bump %s, reason: \"%s\"\n", name, err.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("Bumped %s to %s\n", name, version)
+		if !flags.Commit {
+			continue
+		}
+		if err = commitBump(name, version, changelog); err != nil {
+			fmt.Printf("Failed to commit %s, reason: \"%s\"\n", name, err.Error())
+			os.Exit(1)
+		}
+	}
+}
+
+// groupPending finds the out-of-date packages in a group, either listed or with a matching location
+// upstream or in their package.yml
+func groupPending(group config.Group, releases []db.Release, packages map[string]db.Package) (map[string]db.Release, error) {
+	var match *regexp.Regexp
+	if group.Match != "" {
+		var err error
+		if match, err = regexp.Compile(group.Match); err != nil {
+			return nil, err
+		}
+	}
+	members := make(map[string]bool)
+	for _, name := range group.Packages {
+		members[name] = true
+	}
+	pending := make(map[string]db.Release)
+	for _, r := range releases {
+		if r.Auxiliary || r.Status != db.StatusOutOfDate {
+			continue
+		}
+		if _, ok := pending[r.Package]; ok {
+			continue
+		}
+		matched := match != nil && (match.MatchString(r.Source) || match.MatchString(packages[r.Package].Source))
+		if members[r.Package] || matched {
+			pending[r.Package] = r
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,107 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"fmt"
+	"github.com/DataDrake/cli-ng/cmd"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"github.com/DataDrake/ypkg-update-checker/pkg"
+	"os"
+	"strings"
+)
+
+// Query shows the state of a single package and the packages affected by updating it
+var Query = cmd.CMD{
+	Name:  "query",
+	Alias: "qu",
+	Short: "Show the releases, dependencies and dependents of a package from the last update",
+	Args:  &QueryArgs{},
+	Run:   QueryRun,
+}
+
+// QueryArgs contains the arguments for the "query" subcommand
+type QueryArgs struct {
+	Package string `desc:"Name of the package"`
+}
+
+// QueryRun carries out printing the releases and dependents of a package
+func QueryRun(r *cmd.RootCMD, c *cmd.CMD) {
+	args := c.Args.(*QueryArgs)
+	rdb, err := db.Open()
+	if err != nil {
+		fmt.Printf("Failed to open database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	defer rdb.Close()
+	releases, err := db.GetReleases(rdb, args.Package)
+	if err != nil {
+		fmt.Printf("Failed to read database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	packages, err := db.GetAllPackages(rdb)
+	if err != nil {
+		fmt.Printf("Failed to read database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	distros, err := db.GetAllDistros(rdb)
+	if err != nil {
+		fmt.Printf("Failed to read database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	p, ok := packages[args.Package]
+	if !ok && len(releases) == 0 {
+		fmt.Printf("No package named '%s', try running update first.\n", args.Package)
+		os.Exit(1)
+	}
+	fmt.Printf("Package '%s'", args.Package)
+	if p.Version != "" {
+		fmt.Printf(" %s-%d", p.Version, p.Release)
+	}
+	fmt.Println()
+	for _, release := range releases {
+		name := fmt.Sprintf("Source %d", release.Index)
+		if release.Series != "" {
+			name += " (" + release.Series + ")"
+		}
+		fmt.Printf("    %-20s %-20s %-15s %-15s %s\n", name, db.StatusNames[release.Status], release.Current,
+			release.Latest, release.Source)
+	}
+	if d, ok := distros[args.Package]; ok {
+		fmt.Printf("    Newest in other distributions: %s (%s)\n", d.Version, d.Repo)
+	}
+	graph := pkg.NewGraph(packages)
+	impact := graph.Impact(args.Package)
+	fmt.Printf("\nDepends on: %s\n", joinNames(graph.Dependencies(args.Package)))
+	fmt.Printf("Direct dependents (%d): %s\n", len(impact.Direct), joinNames(impact.Direct))
+	fmt.Printf("Transitive dependents (%d): %s\n", len(impact.Transitive), joinNames(impact.Transitive))
+	if len(impact.Transitive) == 0 {
+		return
+	}
+	fmt.Printf("\nSuggested order to update and rebuild:\n")
+	for i, name := range graph.Order(append([]string{args.Package}, impact.Transitive...)) {
+		fmt.Printf("%5d  %s\n", i+1, name)
+	}
+}
+
+// joinNames lists package names on a single line, or "none"
+func joinNames(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
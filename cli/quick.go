@@ -19,8 +19,8 @@ package cli
 import (
 	"fmt"
 	"github.com/DataDrake/cli-ng/cmd"
-	"github.com/DataDrake/cuppa/providers"
 	"github.com/DataDrake/cuppa/results"
+	"github.com/DataDrake/ypkg-update-checker/config"
 	"github.com/DataDrake/ypkg-update-checker/pkg"
 	"os"
 )
@@ -42,28 +42,38 @@ type QuickArgs struct {
 // QuickRun carries out finding the latest release
 func QuickRun(r *cmd.RootCMD, c *cmd.CMD) {
 	args := c.Args.(*QuickArgs)
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	registry, err := newRegistry(cfg.Providers)
+	if err != nil {
+		fmt.Printf("Failed to set up providers, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
 
 	yml, err := pkg.Open(args.Path)
 	if err != nil {
 		fmt.Printf("Failed to open package.yml, reason: \"%s\"\n", err.Error())
 		os.Exit(1)
 	}
+	for _, finding := range yml.Lint() {
+		fmt.Printf("Lint: %s\n", finding)
+	}
 	found := false
-    fmt.Printf("%#v\n", yml.Sources)
-	for _, p := range providers.All() {
-		for _, srcs := range yml.Sources {
-			for src := range srcs {
-				name := p.Match(src)
-				if name == "" {
-					continue
-				}
-				r, s := p.Latest(name)
-				if s != results.OK || r == nil {
-					continue
-				}
-				found = true
-				fmt.Printf("%s %s %s %s\n", yml.Name, yml.Version, r.Version, r.Location)
+	for _, p := range registry.Providers() {
+		for _, src := range yml.Sources {
+			name := p.Match(src.URL)
+			if name == "" {
+				continue
+			}
+			r, s := p.Latest(name)
+			if s != results.OK || r == nil {
+				continue
 			}
+			found = true
+			fmt.Printf("%s %s %s %s\n", yml.Name, yml.Version, r.Version, r.Location)
 		}
 	}
 	if !found {
@@ -0,0 +1,91 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"fmt"
+	"github.com/DataDrake/cli-ng/cmd"
+	"github.com/DataDrake/ypkg-update-checker/config"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"github.com/DataDrake/ypkg-update-checker/repology"
+	"os"
+	"time"
+)
+
+// Repology imports the versions shipped by other distributions
+var Repology = cmd.CMD{
+	Name:  "repology",
+	Alias: "rp",
+	Short: "Import the newest version of every package in other distributions from a Repology dump",
+	Args:  &RepologyArgs{},
+	Run:   RepologyRun,
+}
+
+// RepologyArgs contains the arguments for the "repology" subcommand
+type RepologyArgs struct {
+	Path string `desc:"Location of a JSON dump of Repology projects"`
+}
+
+// RepologyRun carries out importing a Repology dump, replacing any previous import
+func RepologyRun(r *cmd.RootCMD, c *cmd.CMD) {
+	args := c.Args.(*RepologyArgs)
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	dump, err := repology.Load(args.Path)
+	if err != nil {
+		fmt.Printf("Failed to read dump, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	rdb, err := db.Open()
+	if err != nil {
+		fmt.Printf("Failed to open database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	defer rdb.Close()
+	releases, err := db.GetAllReleases(rdb)
+	if err != nil {
+		fmt.Printf("Failed to read database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	now := time.Now()
+	distros := make([]db.Distro, 0)
+	seen := make(map[string]bool)
+	for _, release := range releases {
+		if seen[release.Package] {
+			continue
+		}
+		seen[release.Package] = true
+		version, repo := dump.Best(release.Package, cfg.Repology.Projects[release.Package], cfg.Repology.Exclude)
+		if version == "" {
+			continue
+		}
+		distros = append(distros, db.Distro{
+			Package:  release.Package,
+			Version:  version,
+			Repo:     repo,
+			Imported: now,
+		})
+	}
+	if err = db.SaveDistros(rdb, distros); err != nil {
+		fmt.Printf("Failed to save versions, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("Found %d of %d packages in other distributions\n", len(distros), len(seen))
+}
@@ -21,7 +21,11 @@ import (
 	"github.com/DataDrake/cli-ng/cmd"
 	"github.com/DataDrake/ypkg-update-checker/db"
 	"github.com/DataDrake/ypkg-update-checker/pkg"
+	"github.com/jmoiron/sqlx"
+	"io"
 	"os"
+	"strconv"
+	"time"
 )
 
 // Report generates a report of the last update
@@ -38,16 +42,97 @@ type ReportArgs struct{}
 
 // ReportRun carries out finding the latest releases
 func ReportRun(r *cmd.RootCMD, c *cmd.CMD) {
+	flags := r.Flags.(*GlobalFlags)
 	rdb, err := db.Open()
 	if err != nil {
 		fmt.Printf("Failed to open database, reason: \"%s\"\n", err.Error())
 		os.Exit(1)
 	}
-	releases, err := db.GetAllReleases(rdb)
+	defer rdb.Close()
+	err = writeReport(os.Stdout, rdb, flags.Format, flags.Since, flags.Sort, flags.SecurityOnly)
 	if err != nil {
-		fmt.Printf("Failed to read database, reason: \"%s\"\n", err.Error())
+		fmt.Printf("Failed to generate report, reason: \"%s\"\n", err.Error())
 		os.Exit(1)
 	}
+}
+
+// writeReport generates either a full report or the changes since a run or time, in HTML or JSON,
+// optionally limited to the matched packages with known security advisories
+func writeReport(w io.Writer, rdb *sqlx.DB, format, since, order string, securityOnly bool) error {
+	if format != "" && format != "html" && format != "json" {
+		return fmt.Errorf("unsupported report format '%s'", format)
+	}
+	releases, err := db.GetAllReleases(rdb)
+	if err != nil {
+		return err
+	}
+	if since != "" {
+		t, err := sinceTime(rdb, since)
+		if err != nil {
+			return fmt.Errorf("invalid value for since '%s': %s", since, err.Error())
+		}
+		prev, err := db.GetHistoryAt(rdb, t)
+		if err != nil {
+			return err
+		}
+		diff := pkg.NewDiff(t, prev, releases)
+		if format == "json" {
+			return diff.PrintJSON(w)
+		}
+		diff.Print(w)
+		return nil
+	}
 	report := pkg.NewReport(releases)
-	report.Print()
+	stats, err := getStats(rdb)
+	if err != nil {
+		return err
+	}
+	report.AddStats(stats)
+	packages, err := db.GetAllPackages(rdb)
+	if err != nil {
+		return err
+	}
+	report.AddPackages(packages)
+	distros, err := db.GetAllDistros(rdb)
+	if err != nil {
+		return err
+	}
+	report.AddDistros(distros)
+	if securityOnly {
+		report.SecurityOnly()
+	}
+	if order != "" {
+		if err = report.Sort(order); err != nil {
+			return err
+		}
+	}
+	if format == "json" {
+		return report.PrintJSON(w)
+	}
+	report.Print(w)
+	return nil
+}
+
+// sinceTimeFormats are the layouts accepted for the time passed to --since
+var sinceTimeFormats = []string{
+	time.RFC3339,
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// sinceTime converts the value of --since into the time to compare against
+func sinceTime(rdb *sqlx.DB, since string) (time.Time, error) {
+	if id, err := strconv.Atoi(since); err == nil {
+		run, err := db.GetRun(rdb, id)
+		return run.Finished, err
+	}
+	var t time.Time
+	var err error
+	for _, format := range sinceTimeFormats {
+		t, err = time.ParseInLocation(format, since, time.Local)
+		if err == nil {
+			break
+		}
+	}
+	return t, err
 }
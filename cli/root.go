@@ -20,8 +20,18 @@ import (
 	"github.com/DataDrake/cli-ng/cmd"
 )
 
+// Version is the release of this tool, recorded with every run
+var Version = "development"
+
 // GlobalFlags contains the flags for all commands
-type GlobalFlags struct{}
+type GlobalFlags struct {
+	Format       string `short:"f" long:"format" arg:"true" desc:"Output format of reports: html (default) or json"`
+	Sort         string `short:"o" long:"sort" arg:"true" desc:"Order of matched packages in reports: name (default), behind, releases, component (grouped), dependents or score"`
+	Since        string `short:"s" long:"since" arg:"true" desc:"Only report changes since a run ID or a time (YYYY-MM-DD[ HH:MM])"`
+	Commit       bool   `short:"c" long:"commit" desc:"Commit a bump to a new branch of the package repository"`
+	Bump         bool   `short:"b" long:"bump" desc:"Bump every package in a plan"`
+	SecurityOnly bool   `short:"x" long:"security-only" desc:"Only list matched packages with known security advisories"`
+}
 
 // Root is the main command for this application
 var Root *cmd.RootCMD
@@ -35,7 +45,18 @@ func init() {
 	}
 	// Setup the Sub-Commands
 	Root.RegisterCMD(&cmd.Help)
+	Root.RegisterCMD(&Bump)
+	Root.RegisterCMD(&Daemon)
+	Root.RegisterCMD(&Digest)
+	Root.RegisterCMD(&History)
+	Root.RegisterCMD(&Plan)
+	Root.RegisterCMD(&Query)
 	Root.RegisterCMD(&Quick)
+	Root.RegisterCMD(&Repology)
 	Root.RegisterCMD(&Report)
+	Root.RegisterCMD(&Runs)
+	Root.RegisterCMD(&Serve)
+	Root.RegisterCMD(&Stats)
+	Root.RegisterCMD(&Todo)
 	Root.RegisterCMD(&Update)
 }
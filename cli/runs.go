@@ -0,0 +1,79 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"fmt"
+	"github.com/DataDrake/cli-ng/cmd"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"github.com/DataDrake/ypkg-update-checker/pkg"
+	"os"
+	"strings"
+	"time"
+)
+
+// Runs lists every recorded invocation of "update"
+var Runs = cmd.CMD{
+	Name:  "runs",
+	Alias: "rs",
+	Short: "List every recorded update run",
+	Args:  &RunsArgs{},
+	Run:   RunsRun,
+}
+
+// RunsArgs contains the arguments for the "runs" subcommand
+type RunsArgs struct{}
+
+// RunsFormat is the format string for a single row of the runs table
+const RunsFormat = "%5v  %-16s  %10v  %8v  %8v  %8v  %-12s  %s\n"
+
+// RunsRun carries out listing the runs
+func RunsRun(r *cmd.RootCMD, c *cmd.CMD) {
+	rdb, err := db.Open()
+	if err != nil {
+		fmt.Printf("Failed to open database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	defer rdb.Close()
+	runs, err := db.GetAllRuns(rdb)
+	if err != nil {
+		fmt.Printf("Failed to read database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	providers, err := db.GetRunProviders(rdb)
+	if err != nil {
+		fmt.Printf("Failed to read database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	if len(runs) == 0 {
+		fmt.Println("No runs recorded.")
+		return
+	}
+	fmt.Printf(RunsFormat, "ID", "Started", "Duration", "Packages", "Checked", "Skipped", "Version", "Errors")
+	for _, run := range runs {
+		errors := make([]string, 0)
+		for _, p := range providers[run.ID] {
+			errors = append(errors, fmt.Sprintf("%s:%d", p.Provider, p.Errors))
+		}
+		if len(errors) == 0 {
+			errors = append(errors, "none")
+		}
+		fmt.Printf(RunsFormat, run.ID, run.Started.Format(pkg.HistoryTimeFormat),
+			run.Finished.Sub(run.Started).Round(time.Second), run.Packages, run.Checked, run.Skipped,
+			run.Version, strings.Join(errors, " "))
+	}
+}
@@ -0,0 +1,235 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/DataDrake/cli-ng/cmd"
+	"github.com/DataDrake/ypkg-update-checker/config"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Serve exposes the release database over HTTP
+var Serve = cmd.CMD{
+	Name:  "serve",
+	Alias: "se",
+	Short: "Serve the report and a JSON API for the releases over HTTP",
+	Args:  &ServeArgs{},
+	Run:   ServeRun,
+}
+
+// ServeArgs contains the arguments for the "serve" subcommand
+type ServeArgs struct {
+	Address string `desc:"Address to listen on (e.g. ':8080')"`
+}
+
+// statusFilters are the values accepted for the "status" filter of the releases API
+var statusFilters = map[string]int{
+	"failed":    db.StatusFailed,
+	"missing":   db.StatusMissingYML,
+	"unmatched": db.StatusUnmatched,
+	"outofdate": db.StatusOutOfDate,
+	"held":      db.StatusHeldBack,
+	"uptodate":  db.StatusUpToDate,
+	"ahead":     db.StatusAhead,
+}
+
+// server handles every request with a single database connection, rechecking packages with a checker
+type server struct {
+	rdb    *sqlx.DB
+	checks *checker
+}
+
+// ServeRun carries out serving the releases until killed
+func ServeRun(r *cmd.RootCMD, c *cmd.CMD) {
+	args := c.Args.(*ServeArgs)
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	db.MaxAge = cfg.MaxAge.Duration
+	rdb, err := db.Open()
+	if err != nil {
+		fmt.Printf("Failed to open database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	defer rdb.Close()
+	checks, err := newChecker(rdb, ".", cfg)
+	if err != nil {
+		fmt.Printf("Failed to set up checks, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("Listening on %s\n", args.Address)
+	err = http.ListenAndServe(args.Address, newServer(checks))
+	if err != nil {
+		fmt.Printf("Failed to serve, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+// newServer sets up the routes of the HTTP server
+func newServer(checks *checker) http.Handler {
+	s := &server{checks.rdb, checks}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.report)
+	mux.HandleFunc("/api/releases", s.releases)
+	mux.HandleFunc("/api/packages/", s.packages)
+	return mux
+}
+
+// report renders a report, taking the same options as the "report" subcommand as query parameters
+func (s *server) report(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "only GET is supported")
+		return
+	}
+	query := req.URL.Query()
+	securityOnly := false
+	if value := query.Get("security_only"); value != "" {
+		var err error
+		if securityOnly, err = strconv.ParseBool(value); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid security_only '"+value+"'")
+			return
+		}
+	}
+	format := query.Get("format")
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+	err := writeReport(w, s.rdb, format, query.Get("since"), query.Get("sort"), securityOnly)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+	}
+}
+
+// releases lists every release, optionally filtered by package, status or provider
+func (s *server) releases(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "only GET is supported")
+		return
+	}
+	query := req.URL.Query()
+	status, filterStatus := 0, query.Get("status") != ""
+	if filterStatus {
+		var ok bool
+		if status, ok = statusFilters[query.Get("status")]; !ok {
+			writeError(w, http.StatusBadRequest, "unknown status '"+query.Get("status")+"'")
+			return
+		}
+	}
+	all, err := db.GetAllReleases(s.rdb)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	releases := make([]db.Release, 0)
+	for _, r := range all {
+		if name := query.Get("package"); name != "" && r.Package != name {
+			continue
+		}
+		if provider := query.Get("provider"); provider != "" && r.Provider != provider {
+			continue
+		}
+		if filterStatus && r.Status != status {
+			continue
+		}
+		releases = append(releases, r)
+	}
+	writeJSON(w, http.StatusOK, releases)
+}
+
+// packageDetails is the response for a single package
+type packageDetails struct {
+	Package  *db.Package   `json:"package,omitempty"`
+	Releases []db.Release  `json:"releases"`
+	History  []db.Snapshot `json:"history"`
+}
+
+// packages gets a single package with its history, or rechecks it with "POST .../<name>/check"
+func (s *server) packages(w http.ResponseWriter, req *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(req.URL.Path, "/api/packages/"), "/")
+	pieces := strings.Split(path, "/")
+	name := pieces[0]
+	if name == "" || name == "." || name == ".." {
+		http.NotFound(w, req)
+		return
+	}
+	switch {
+	case len(pieces) == 1 && req.Method == http.MethodGet:
+	case len(pieces) == 2 && pieces[1] == "check" && req.Method == http.MethodPost:
+		if info, err := os.Stat(filepath.Join(s.checks.dir, name)); err != nil || !info.IsDir() {
+			writeError(w, http.StatusNotFound, "no such package '"+name+"'")
+			return
+		}
+		if err := s.checks.checkPackage(&db.Run{}, name, true); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	case len(pieces) <= 2:
+		writeError(w, http.StatusMethodNotAllowed, "unsupported method "+req.Method)
+		return
+	default:
+		http.NotFound(w, req)
+		return
+	}
+	var details packageDetails
+	var err error
+	if details.Releases, err = db.GetReleases(s.rdb, name); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(details.Releases) == 0 {
+		writeError(w, http.StatusNotFound, "no such package '"+name+"'")
+		return
+	}
+	if details.History, err = db.GetHistory(s.rdb, name); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if p, err := db.GetPackage(s.rdb, name); err == nil {
+		details.Package = &p
+	}
+	writeJSON(w, http.StatusOK, details)
+}
+
+// writeJSON sends any value as a JSON response
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	enc.Encode(v)
+}
+
+// writeError sends an error message as a JSON response
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
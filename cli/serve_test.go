@@ -0,0 +1,48 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"github.com/DataDrake/ypkg-update-checker/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServeSecurityOnly(t *testing.T) {
+	t.Parallel()
+	checks, _, teardown := setupFixtures(t)
+	defer teardown()
+	if _, err := checks.updateAll(config.Default.Weights); err != nil {
+		t.Fatalf("Update failed: %s", err)
+	}
+	handler := newServer(checks)
+	tests := map[string]int{
+		"/?format=json":                     http.StatusOK,
+		"/?format=json&security_only=true":  http.StatusOK,
+		"/?format=json&security_only=false": http.StatusOK,
+		"/?format=json&security_only=0":     http.StatusOK,
+		"/?format=json&security_only=maybe": http.StatusBadRequest,
+	}
+	for target, code := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != code {
+			t.Errorf("Expected %d for '%s', found %d", code, target, w.Code)
+		}
+	}
+}
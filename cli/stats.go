@@ -0,0 +1,67 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"fmt"
+	"github.com/DataDrake/cli-ng/cmd"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"github.com/DataDrake/ypkg-update-checker/pkg"
+	"github.com/jmoiron/sqlx"
+	"os"
+)
+
+// Stats shows how the state of the repository changed across every run
+var Stats = cmd.CMD{
+	Name:  "stats",
+	Alias: "st",
+	Short: "Show trends across every recorded update run",
+	Args:  &StatsArgs{},
+	Run:   StatsRun,
+}
+
+// StatsArgs contains the arguments for the "stats" subcommand
+type StatsArgs struct{}
+
+// StatsRun carries out printing the trends
+func StatsRun(r *cmd.RootCMD, c *cmd.CMD) {
+	rdb, err := db.Open()
+	if err != nil {
+		fmt.Printf("Failed to open database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	defer rdb.Close()
+	stats, err := getStats(rdb)
+	if err != nil {
+		fmt.Printf("Failed to read database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	stats.Print()
+}
+
+// getStats builds the trends from every recorded run
+func getStats(rdb *sqlx.DB) (*pkg.Stats, error) {
+	runs, err := db.GetAllRuns(rdb)
+	if err != nil {
+		return nil, err
+	}
+	providers, err := db.GetRunProviders(rdb)
+	if err != nil {
+		return nil, err
+	}
+	return pkg.NewStats(runs, providers), nil
+}
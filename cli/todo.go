@@ -0,0 +1,89 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"fmt"
+	"github.com/DataDrake/cli-ng/cmd"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"github.com/DataDrake/ypkg-update-checker/pkg"
+	"os"
+	"sort"
+)
+
+// Todo lists the pending updates, most urgent first
+var Todo = cmd.CMD{
+	Name:  "todo",
+	Alias: "t",
+	Short: "List the out of date packages by the priority of updating them",
+	Args:  &TodoArgs{},
+	Run:   TodoRun,
+}
+
+// TodoArgs contains the arguments for the "todo" subcommand
+type TodoArgs struct{}
+
+// TodoFormat is the format string for a single row of the todo list
+const TodoFormat = "%5v  %-30s  %-15s  %-15s  %-10s  %6v\n"
+
+// TodoRun carries out listing the out of date packages by score, only those with known advisories with --security-only
+func TodoRun(r *cmd.RootCMD, c *cmd.CMD) {
+	flags := r.Flags.(*GlobalFlags)
+	rdb, err := db.Open()
+	if err != nil {
+		fmt.Printf("Failed to open database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	defer rdb.Close()
+	releases, err := db.GetAllReleases(rdb)
+	if err != nil {
+		fmt.Printf("Failed to read database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	pending := make([]db.Release, 0)
+	for _, release := range releases {
+		if flags.SecurityOnly && len(release.CVEs) == 0 {
+			continue
+		}
+		if release.Status == db.StatusOutOfDate && !release.Auxiliary {
+			pending = append(pending, release)
+		}
+	}
+	if len(pending) == 0 && flags.SecurityOnly {
+		fmt.Println("Nothing to do, no out of date package has known security advisories.")
+		return
+	}
+	if len(pending) == 0 {
+		fmt.Println("Nothing to do, every matched package is up to date.")
+		return
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].Score > pending[j].Score
+	})
+	fmt.Printf(TodoFormat, "Rank", "Name", "Old Version", "New Version", "Behind For", "Score")
+	for i, release := range pending {
+		name := release.Package
+		if release.Series != "" {
+			name += " (" + release.Series + ")"
+		}
+		if len(release.CVEs) > 0 {
+			name += " [security]"
+		}
+		fmt.Printf(TodoFormat, i+1, name, release.Current, release.Latest, pkg.FormatDuration(release.TimeBehind()),
+			fmt.Sprintf("%.1f", release.Score))
+	}
+}
@@ -19,13 +19,18 @@ package cli
 import (
 	"fmt"
 	"github.com/DataDrake/cli-ng/cmd"
+	"github.com/DataDrake/ypkg-update-checker/config"
+	"github.com/DataDrake/ypkg-update-checker/custom"
 	"github.com/DataDrake/ypkg-update-checker/db"
+	"github.com/DataDrake/ypkg-update-checker/github"
+	"github.com/DataDrake/ypkg-update-checker/notify"
 	"github.com/DataDrake/ypkg-update-checker/pkg"
+	"github.com/DataDrake/ypkg-update-checker/security"
 	"github.com/jmoiron/sqlx"
 	"io/ioutil"
 	"os"
 	"path/filepath"
-    "runtime"
+	"runtime"
 	"time"
 )
 
@@ -41,50 +46,60 @@ var Update = cmd.CMD{
 // UpdateArgs contains the arguments for the "update" subcommand
 type UpdateArgs struct{}
 
-func updateCheck(rdb *sqlx.DB, in chan string, quit chan bool) {
+// checker looks up the releases of the packages in a directory and saves them
+type checker struct {
+	rdb *sqlx.DB
+	// dir holds a directory for each package
+	dir string
+	// registry supplies the providers that every source is looked up with
+	registry db.Registry
+	// notifier announces the transitions found, when any webhooks are configured
+	notifier *notify.Notifier
+	// vulnerabilities is the local feed that packaged versions are checked against, if configured
+	vulnerabilities *security.Feed
+	// products maps package names to their identifiers in the vulnerability feed
+	products map[string]string
+}
+
+// newChecker sets up a checker for the packages in a directory with everything in the config
+func newChecker(rdb *sqlx.DB, dir string, cfg config.Config) (c *checker, err error) {
+	c = &checker{rdb: rdb, dir: dir}
+	if c.notifier, err = notify.New(rdb, cfg.Webhooks); err != nil {
+		return nil, fmt.Errorf("bad webhooks: %s", err.Error())
+	}
+	if err = c.loadSecurity(cfg.Security); err != nil {
+		return nil, fmt.Errorf("bad vulnerability feed: %s", err.Error())
+	}
+	if c.registry, err = newRegistry(cfg.Providers); err != nil {
+		return nil, fmt.Errorf("bad providers: %s", err.Error())
+	}
+	return c, nil
+}
+
+// loadSecurity reads the vulnerability feed, if configured
+func (c *checker) loadSecurity(cfg config.Security) (err error) {
+	c.vulnerabilities, c.products = nil, cfg.Products
+	if cfg.Feed == "" {
+		return
+	}
+	c.vulnerabilities, err = security.Load(cfg.Feed)
+	return
+}
+
+// newRegistry sets up the providers defined in the config, alongside the ones built into cuppa
+func newRegistry(cfgs []config.Provider) (db.Registry, error) {
+	all, err := custom.Compile(cfgs)
+	if err != nil {
+		return nil, err
+	}
+	return db.CuppaRegistry{Custom: all}, nil
+}
+
+func (c *checker) updateCheck(run *db.Run, in chan string, quit chan bool) {
 	for {
 		select {
 		case p := <-in:
-			prev, err := db.GetReleases(rdb, p)
-			if err != nil {
-				fmt.Printf("Failed to get releases for %s, reason: %s", p, err.Error())
-				continue
-			}
-			curr := make([]db.Release, 0)
-			yml, err := pkg.Open(filepath.Join(".", p, "package.yml"))
-			if err != nil {
-				curr = append(curr,
-					db.Release{
-						Package: p,
-						Updated: time.Now(),
-						Index:   0,
-						Status:  db.StatusMissingYML,
-					},
-				)
-				fmt.Fprintf(os.Stderr, "%s failed, reason: %s\n", p, err.Error())
-			} else {
-				for index, src := range yml.Sources {
-					var r db.Release
-					for location := range src {
-						if len(prev) > index {
-    					    r = prev[index]
-						} else {
-							r = db.Release{
-								Package: p,
-                                Source: location,
-								Current: yml.Version,
-								Latest:  "N/A",
-								Updated: time.Now().Add(-6*time.Hour),
-								Index:   index,
-                                Status: db.StatusUnmatched,
-							}
-						}
-					}
-					r = r.Check(rdb)
-					curr = append(curr, r)
-				}
-			}
-			err = db.UpdatePackage(rdb, curr)
+			err := c.checkPackage(run, p, false)
 			if err != nil {
 				fmt.Printf("Failed to update %s, reason: %s\n", p, err.Error())
 			}
@@ -94,20 +109,157 @@ func updateCheck(rdb *sqlx.DB, in chan string, quit chan bool) {
 	}
 }
 
-var updateWorkers = runtime.NumCPU()
+// checkPackage finds the latest release of every source of a package and saves the results,
+// looking up every source again if forced or if the packaged version has changed.
+// Only the first source is compared with the version of the package, the rest with the version in their URL.
+func (c *checker) checkPackage(run *db.Run, p string, force bool) error {
+	prev, err := db.GetReleases(c.rdb, p)
+	if err != nil {
+		return err
+	}
+	curr := make([]db.Release, 0)
+	yml, err := pkg.Open(filepath.Join(c.dir, p, "package.yml"))
+	if err != nil {
+		curr = append(curr,
+			db.Release{
+				Package: p,
+				Updated: time.Now(),
+				Index:   0,
+				Status:  db.StatusMissingYML,
+			},
+		)
+		fmt.Fprintf(os.Stderr, "%s failed, reason: %s\n", p, err.Error())
+	} else {
+		if err = db.SavePackage(c.rdb, yml.Metadata(p)); err != nil {
+			return err
+		}
+		monitoring, err := c.openMonitoring(p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Ignoring monitoring.yml of %s, reason: %s\n", p, err.Error())
+		}
+		// Rows are matched by source and series, so that adding either does not move the others
+		next := 0
+		for _, r := range prev {
+			if r.Index >= next {
+				next = r.Index + 1
+			}
+		}
+		claimed := make(map[int]bool)
+		for index, src := range yml.Sources {
+			// Overrides describe the upstream of the package itself, not any extra sources,
+			// and every series tracked by them gets a row of its own
+			tracked := []*db.Monitoring{nil}
+			if index == 0 && len(monitoring) > 0 {
+				tracked = monitoring
+			}
+			current := yml.Version
+			if index > 0 {
+				current = src.Version()
+			}
+			packaged := packagedSeries(tracked, current)
+			for _, m := range tracked {
+				series := ""
+				if m != nil {
+					series = m.Series
+				}
+				r := db.Release{
+					Package: p,
+					Source:  src.URL,
+					Current: current,
+					Latest:  "N/A",
+					Updated: time.Now().Add(-6 * time.Hour),
+					Index:   next,
+					Status:  db.StatusUnmatched,
+				}
+				found := false
+				for _, old := range prev {
+					if !claimed[old.Index] && old.Tracks(index, series) {
+						r, found = old, true
+						break
+					}
+				}
+				if found {
+					claimed[r.Index] = true
+				} else {
+					next++
+				}
+				if r.Provider == "" {
+					// Not replaced by the location of a matched release yet
+					r.Source = src.URL
+				}
+				if force || r.Current != current || r.Ref != src.Ref || r.Series != series {
+					r.Current = current
+					r.Ref = src.Ref
+					r.Series = series
+					r.Updated = time.Time{}
+				}
+				r.SourceIndex = index
+				r.Monitoring = m
+				r.Auxiliary = index > 0
+				if m == packaged && series != "" && r.Status == db.StatusHeldBack {
+					// Only shown for information before, so compared again
+					r.Status = db.StatusUnmatched
+				}
+				r.CVEs = nil
+				if !r.Auxiliary && m == packaged {
+					// Advisories are about the packaged version, so not repeated on the other series
+					r.CVEs = c.vulnerabilities.Match(c.products[p], current)
+				}
+				r.Advisories = len(r.CVEs)
+				if r.Auxiliary && current == "" && !github.IsCommit(src.Ref) {
+					// Nothing to compare with, so not worth looking up
+					r.Latest = "N/A"
+					r.Provider = ""
+					r.Status = db.StatusUnmatched
+				} else {
+					r = r.Check(c.registry, run)
+				}
+				if m != packaged && r.Status >= db.StatusOutOfDate {
+					// Another series than the packaged one, only shown for information
+					r.Status = db.StatusHeldBack
+					r.Behind = 0
+				}
+				curr = append(curr, r)
+			}
+		}
+	}
+	if err = db.UpdatePackage(c.rdb, curr); err != nil {
+		return err
+	}
+	if err = c.notifier.Notify(prev, curr); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to send notifications for %s, reason: %s\n", p, err.Error())
+	}
+	return nil
+}
 
-// UpdateRun carries out finding the latest releases
-func UpdateRun(r *cmd.RootCMD, c *cmd.CMD) {
-	rdb, err := db.Open()
+// packagedSeries picks which of the tracked series is compared with the version of a package, which is the first
+// one it belongs to, or the first one if it belongs to none. The rest are only shown for information.
+func packagedSeries(tracked []*db.Monitoring, current string) *db.Monitoring {
+	for _, m := range tracked {
+		if m != nil && db.InSeries(db.NewVersion(current), db.NewVersion(m.Series)) {
+			return m
+		}
+	}
+	return tracked[0]
+}
+
+// openMonitoring reads the optional monitoring.yml of a package, with one Monitoring per tracked series
+func (c *checker) openMonitoring(p string) ([]*db.Monitoring, error) {
+	m, err := pkg.OpenMonitoring(filepath.Join(c.dir, p, "monitoring.yml"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
 	if err != nil {
-		fmt.Printf("Failed to open database, reason: \"%s\"\n", err.Error())
-		os.Exit(1)
+		return nil, err
 	}
-	defer rdb.Close()
-	files, err := ioutil.ReadDir(".")
+	return m.Compile(c.registry)
+}
+
+// scanPackages lists every package in the directory and forgets any that were removed
+func (c *checker) scanPackages() ([]string, error) {
+	files, err := ioutil.ReadDir(c.dir)
 	if err != nil {
-		fmt.Printf("Failed to get packages, reason: \"%s\"\n", err.Error())
-		os.Exit(1)
+		return nil, err
 	}
 	packages := make([]string, 0)
 	for _, file := range files {
@@ -119,15 +271,69 @@ func UpdateRun(r *cmd.RootCMD, c *cmd.CMD) {
 		}
 		packages = append(packages, file.Name())
 	}
-	err = db.CleanPackages(rdb, packages)
+	err = db.CleanPackages(c.rdb, packages)
+	return packages, err
+}
+
+// rescorePackages sets the priority of every release, once all of the packages have been checked
+func rescorePackages(rdb *sqlx.DB, w config.Weights) error {
+	releases, err := db.GetAllReleases(rdb)
+	if err != nil {
+		return err
+	}
+	packages, err := db.GetAllPackages(rdb)
 	if err != nil {
-		fmt.Printf("Failed to clean up packages, reason: \"%s\"\n", err.Error())
+		return err
+	}
+	return db.SaveScores(rdb, pkg.Rescore(releases, packages, w))
+}
+
+var updateWorkers = runtime.NumCPU()
+
+// UpdateRun carries out finding the latest releases
+func UpdateRun(r *cmd.RootCMD, c *cmd.CMD) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	db.MaxAge = cfg.MaxAge.Duration
+	rdb, err := db.Open()
+	if err != nil {
+		fmt.Printf("Failed to open database, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	defer rdb.Close()
+	checks, err := newChecker(rdb, ".", cfg)
+	if err != nil {
+		fmt.Printf("Failed to set up checks, reason: \"%s\"\n", err.Error())
 		os.Exit(1)
 	}
+	run, err := checks.updateAll(cfg.Weights)
+	// Waits for the webhooks, which are sent in the background
+	checks.notifier.Close()
+	if err != nil {
+		fmt.Printf("Failed to update, reason: \"%s\"\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("Finished run %d\n", run.ID)
+	os.Exit(0)
+}
+
+// updateAll checks every package in the directory as a single run, then scores them
+func (c *checker) updateAll(w config.Weights) (*db.Run, error) {
+	run, err := db.NewRun(c.rdb, Version)
+	if err != nil {
+		return nil, err
+	}
+	packages, err := c.scanPackages()
+	if err != nil {
+		return nil, err
+	}
 	in := make(chan string)
 	quit := make(chan bool)
 	for i := 0; i < updateWorkers; i++ {
-		go updateCheck(rdb, in, quit)
+		go c.updateCheck(run, in, quit)
 	}
 	for _, p := range packages {
 		in <- p
@@ -135,5 +341,8 @@ func UpdateRun(r *cmd.RootCMD, c *cmd.CMD) {
 	for i := 0; i < updateWorkers; i++ {
 		quit <- true
 	}
-	os.Exit(0)
+	if err = rescorePackages(c.rdb, w); err != nil {
+		return nil, err
+	}
+	return run, run.Finish(c.rdb, len(packages))
 }
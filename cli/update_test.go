@@ -0,0 +1,384 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/DataDrake/ypkg-update-checker/config"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"github.com/DataDrake/ypkg-update-checker/fake"
+	"github.com/DataDrake/ypkg-update-checker/pkg"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupChecker checks the package tree in dir against the providers of registry, saving to an empty
+// in-memory database. The returned function closes the database.
+func setupChecker(t *testing.T, dir string, registry db.Registry) (*checker, func()) {
+	rdb, err := db.Connect(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %s", err)
+	}
+	checks := &checker{
+		rdb:      rdb,
+		dir:      dir,
+		registry: registry,
+	}
+	return checks, func() {
+		rdb.Close()
+	}
+}
+
+// setupFixtures checks the fixture package tree with the fake provider in place of cuppa
+func setupFixtures(t *testing.T) (*checker, *fake.Provider, func()) {
+	provider, err := fake.Load(filepath.Join("testdata", "releases.json"))
+	if err != nil {
+		t.Fatalf("Failed to load fixture releases: %s", err)
+	}
+	checks, teardown := setupChecker(t, filepath.Join("testdata", "repo"), db.ProviderList{provider})
+	return checks, provider, teardown
+}
+
+// releasesByPackage gets the first release of every package
+func releasesByPackage(t *testing.T, rdb *sqlx.DB) map[string]db.Release {
+	releases, err := db.GetAllReleases(rdb)
+	if err != nil {
+		t.Fatalf("Failed to read releases: %s", err)
+	}
+	found := make(map[string]db.Release)
+	for _, r := range releases {
+		if r.Index == 0 {
+			found[r.Package] = r
+		}
+	}
+	return found
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+	checks, _, teardown := setupFixtures(t)
+	defer teardown()
+	run, err := checks.updateAll(config.Default.Weights)
+	if err != nil {
+		t.Fatalf("Update failed: %s", err)
+	}
+	// Without a package.yml, there is nothing to look up
+	if run.Packages != 5 || run.Checked != 4 {
+		t.Errorf("Expected 4 of 5 packages checked, found %d of %d", run.Checked, run.Packages)
+	}
+	releases := releasesByPackage(t, checks.rdb)
+	if _, ok := releases["common"]; ok {
+		t.Error("Expected common to be skipped")
+	}
+	expected := map[string]int{
+		"foo":    db.StatusOutOfDate,
+		"bar":    db.StatusUpToDate,
+		"baz":    db.StatusUnmatched,
+		"qux":    db.StatusFailed,
+		"broken": db.StatusMissingYML,
+	}
+	for name, status := range expected {
+		r, ok := releases[name]
+		if !ok {
+			t.Errorf("Expected a release for %s", name)
+			continue
+		}
+		if r.Status != status {
+			t.Errorf("Expected %s to be '%s', found '%s'", name, db.StatusNames[status], db.StatusNames[r.Status])
+		}
+	}
+	foo := releases["foo"]
+	if foo.Latest != "1.2" || foo.Behind != 2 || foo.Provider != "Fake" {
+		t.Errorf("Expected foo to be 2 releases behind 1.2 from Fake, found %d behind %s from %s",
+			foo.Behind, foo.Latest, foo.Provider)
+	}
+	if foo.Source != "https://example.com/foo/foo-1.2.tar.gz" {
+		t.Errorf("Expected the location of foo 1.2, found '%s'", foo.Source)
+	}
+	if foo.Score <= 0 || releases["bar"].Score != 0 {
+		t.Errorf("Expected only foo to be scored, found %.1f and %.1f", foo.Score, releases["bar"].Score)
+	}
+	p, err := db.GetPackage(checks.rdb, "baz")
+	if err != nil {
+		t.Fatalf("Failed to read package: %s", err)
+	}
+	if p.Component != "programming.library" || len(p.BuildDeps) != 1 || p.BuildDeps[0] != "foo-devel" {
+		t.Errorf("Unexpected metadata for baz: %+v", p)
+	}
+}
+
+func TestUpdateSkipsFresh(t *testing.T) {
+	t.Parallel()
+	checks, provider, teardown := setupFixtures(t)
+	defer teardown()
+	if _, err := checks.updateAll(config.Default.Weights); err != nil {
+		t.Fatalf("Update failed: %s", err)
+	}
+	lookups := provider.Lookups("foo")
+	if lookups == 0 {
+		t.Fatal("Expected foo to be looked up")
+	}
+	run, err := checks.updateAll(config.Default.Weights)
+	if err != nil {
+		t.Fatalf("Update failed: %s", err)
+	}
+	if provider.Lookups("foo") != lookups {
+		t.Errorf("Expected foo not to be looked up again, found %d lookups", provider.Lookups("foo")-lookups)
+	}
+	// Only the matched packages are still fresh
+	if run.Skipped != 2 {
+		t.Errorf("Expected 2 packages skipped, found %d", run.Skipped)
+	}
+	if releasesByPackage(t, checks.rdb)["foo"].Status != db.StatusOutOfDate {
+		t.Error("Expected foo to still be out of date")
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	t.Parallel()
+	checks, _, teardown := setupFixtures(t)
+	defer teardown()
+	if _, err := checks.updateAll(config.Default.Weights); err != nil {
+		t.Fatalf("Update failed: %s", err)
+	}
+	var buff bytes.Buffer
+	if err := writeReport(&buff, checks.rdb, "json", "", "score", false); err != nil {
+		t.Fatalf("Report failed: %s", err)
+	}
+	var report struct {
+		Summary map[string]int        `json:"summary"`
+		Matched []db.Release          `json:"matched"`
+		Impact  map[string]pkg.Impact `json:"impact"`
+		Order   []string              `json:"update_order"`
+	}
+	if err := json.Unmarshal(buff.Bytes(), &report); err != nil {
+		t.Fatalf("Report is not valid JSON: %s", err)
+	}
+	expected := map[string]int{
+		"out_of_date": 1,
+		"up_to_date":  1,
+		"unmatched":   1,
+		"failed":      2,
+		"total":       5,
+	}
+	for key, count := range expected {
+		if report.Summary[key] != count {
+			t.Errorf("Expected %d for '%s', found %d", count, key, report.Summary[key])
+		}
+	}
+	if len(report.Matched) != 2 || report.Matched[0].Package != "foo" {
+		t.Errorf("Expected foo to be the most urgent of 2 matched packages, found %+v", report.Matched)
+	}
+	impact := report.Impact["foo"]
+	if strings.Join(impact.Direct, ",") != "bar,baz" || len(impact.Transitive) != 2 {
+		t.Errorf("Expected bar and baz to depend on foo, found %+v", impact)
+	}
+	if strings.Join(report.Order, ",") != "foo" {
+		t.Errorf("Expected only foo in the update order, found %v", report.Order)
+	}
+}
+
+func TestReportHTML(t *testing.T) {
+	t.Parallel()
+	checks, _, teardown := setupFixtures(t)
+	defer teardown()
+	if _, err := checks.updateAll(config.Default.Weights); err != nil {
+		t.Fatalf("Update failed: %s", err)
+	}
+	var buff bytes.Buffer
+	if err := writeReport(&buff, checks.rdb, "", "", "", false); err != nil {
+		t.Fatalf("Report failed: %s", err)
+	}
+	html := buff.String()
+	for _, expected := range []string{
+		"<td class=\"behind\">1.2</td>",
+		"<td class=\"ok\">2.0</td>",
+		"https://unknown.example.org/baz-0.5.tar.gz",
+		"<h1 id=\"order\">Suggested Update Order</h1>",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("Expected the report to contain '%s'", expected)
+		}
+	}
+	buff.Reset()
+	if err := writeReport(&buff, checks.rdb, "", "", "component", false); err != nil {
+		t.Fatalf("Report failed: %s", err)
+	}
+	// foo and bar are the only matched packages, both in system.utils
+	if strings.Count(buff.String(), "colspan") != 1 || !strings.Contains(buff.String(), ">system.utils</th>") {
+		t.Error("Expected a single sub-header for the component of the matched packages")
+	}
+	if strings.Contains(html, "colspan") {
+		t.Error("Expected no sub-headers unless sorting by component")
+	}
+	if err := writeReport(&buff, checks.rdb, "xml", "", "", false); err == nil {
+		t.Error("Expected an unsupported format to fail")
+	}
+}
+
+// seriesPackage is a package.yml with an extra source, for a package tracking several series
+const seriesPackage = `name       : py
+version    : 3.11.2
+release    : 1
+source     :
+    - https://example.com/py/py-3.11.2.tar.gz : 1f0c7e2e8a1e2f3a9b2d0c6e5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b
+    - https://example.com/data/data-1.0.tar.gz : 2f0c7e2e8a1e2f3a9b2d0c6e5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b
+license    : MIT
+component  : programming.python
+summary    : Interpreter shipped in several series
+description: |
+    Interpreter shipped in several series
+`
+
+// seriesProvider publishes several series of the package in seriesPackage, and one newer extra source
+func seriesProvider() *fake.Provider {
+	published := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	return fake.New("Fake", map[string]fake.Project{
+		"py": {
+			Sources: []string{"https://example.com/py/"},
+			Releases: []fake.Release{
+				{Version: "3.10.9", Location: "https://example.com/py/py-3.10.9.tar.gz", Published: published},
+				{Version: "3.11.2", Location: "https://example.com/py/py-3.11.2.tar.gz", Published: published},
+				{Version: "3.11.4", Location: "https://example.com/py/py-3.11.4.tar.gz", Published: published},
+				{Version: "3.12.1", Location: "https://example.com/py/py-3.12.1.tar.gz", Published: published},
+			},
+		},
+		"data": {
+			Sources: []string{"https://example.com/data/"},
+			Releases: []fake.Release{
+				{Version: "1.1", Location: "https://example.com/data/data-1.1.tar.gz", Published: published},
+			},
+		},
+	})
+}
+
+// seriesTree creates a temporary package tree holding only seriesPackage, tracking a list of series
+func seriesTree(t *testing.T, series string) string {
+	dir, err := ioutil.TempDir("", "series")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Mkdir(filepath.Join(dir, "py"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "py", "package.yml"), []byte(seriesPackage), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeSeries(t, dir, series)
+	return dir
+}
+
+// writeSeries replaces the monitoring.yml of the package from seriesTree
+func writeSeries(t *testing.T, dir string, series string) {
+	path := filepath.Join(dir, "py", "monitoring.yml")
+	if err := ioutil.WriteFile(path, []byte("series: "+series+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// releasesBySeries gets every release of the package from seriesTree, by series or "aux" for the extra source
+func releasesBySeries(t *testing.T, rdb *sqlx.DB) map[string]db.Release {
+	releases, err := db.GetReleases(rdb, "py")
+	if err != nil {
+		t.Fatalf("Failed to read releases: %s", err)
+	}
+	found := make(map[string]db.Release)
+	for _, r := range releases {
+		if r.Auxiliary {
+			found["aux"] = r
+		} else {
+			found[r.Series] = r
+		}
+	}
+	return found
+}
+
+func TestUpdateSeries(t *testing.T) {
+	t.Parallel()
+	dir := seriesTree(t, "['3.11', '3.12']")
+	defer os.RemoveAll(dir)
+	checks, teardown := setupChecker(t, dir, db.ProviderList{seriesProvider()})
+	defer teardown()
+	feed := filepath.Join(checks.dir, "feed.json")
+	advisories := `[{"id": "PY-<1>", "affected": [{"package": {"name": "py"}, "ranges": [
+    {"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "3.11.3"}]}
+]}]}]`
+	if err := ioutil.WriteFile(feed, []byte(advisories), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checks.loadSecurity(config.Security{Feed: feed, Products: map[string]string{"py": "py"}}); err != nil {
+		t.Fatalf("Failed to load feed: %s", err)
+	}
+	if err := checks.checkPackage(&db.Run{}, "py", false); err != nil {
+		t.Fatalf("Check failed: %s", err)
+	}
+	before := releasesBySeries(t, checks.rdb)
+	if before["3.11"].Advisories != 1 || before["3.12"].Advisories != 0 {
+		t.Errorf("Expected only the packaged series to have an advisory, found %v and %v",
+			before["3.11"].CVEs, before["3.12"].CVEs)
+	}
+	var buff bytes.Buffer
+	if err := writeReport(&buff, checks.rdb, "", "", "", true); err != nil {
+		t.Fatalf("Report failed: %s", err)
+	}
+	if strings.Contains(buff.String(), "PY-<1>") || strings.Count(buff.String(), ">PY-&lt;1&gt;<") != 1 {
+		t.Error("Expected the advisory to be listed once and escaped")
+	}
+	if r := before["3.11"]; r.Status != db.StatusOutOfDate || r.Latest != "3.11.4" {
+		t.Errorf("Expected the packaged series to be out of date with 3.11.4, found '%s' with %s",
+			db.StatusNames[r.Status], r.Latest)
+	}
+	if r := before["3.12"]; r.Status != db.StatusHeldBack || r.Latest != "3.12.1" || r.Behind != 0 {
+		t.Errorf("Expected the other series to be held back at 3.12.1, found '%s' with %s",
+			db.StatusNames[r.Status], r.Latest)
+	}
+	if r := before["aux"]; r.Status != db.StatusOutOfDate || r.Latest != "1.1" {
+		t.Errorf("Expected the extra source to be out of date with 1.1, found '%s' with %s",
+			db.StatusNames[r.Status], r.Latest)
+	}
+	// Tracking another series must not move the existing rows
+	writeSeries(t, dir, "['3.10', '3.11', '3.12']")
+	if err := checks.checkPackage(&db.Run{}, "py", true); err != nil {
+		t.Fatalf("Check failed: %s", err)
+	}
+	after := releasesBySeries(t, checks.rdb)
+	if len(after) != 4 {
+		t.Fatalf("Expected 4 rows, found %d", len(after))
+	}
+	for _, key := range []string{"3.11", "3.12", "aux"} {
+		if after[key].Index != before[key].Index {
+			t.Errorf("Expected the row of '%s' to keep index %d, found %d", key, before[key].Index, after[key].Index)
+		}
+	}
+	if after["3.10"].Status != db.StatusHeldBack {
+		t.Errorf("Expected the new series to be held back, found '%s'", db.StatusNames[after["3.10"].Status])
+	}
+	history, err := db.GetHistory(checks.rdb, "py")
+	if err != nil {
+		t.Fatalf("Failed to read history: %s", err)
+	}
+	if len(history) != 4 {
+		t.Errorf("Expected a snapshot for each of the 4 rows, found %d", len(history))
+	}
+}
@@ -0,0 +1,202 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package config
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"os"
+	"os/user"
+	"time"
+)
+
+// Duration is a time.Duration written as a string in the config (e.g. "4h")
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText parses a Duration from the config
+func (d *Duration) UnmarshalText(text []byte) (err error) {
+	d.Duration, err = time.ParseDuration(string(text))
+	return
+}
+
+// Report is a single report to be regenerated by the daemon
+type Report struct {
+	Path   string `toml:"path"`
+	Format string `toml:"format"`
+	Since  string `toml:"since"`
+	Sort   string `toml:"sort"`
+	// SecurityOnly leaves out the matched packages without any known advisories
+	SecurityOnly bool `toml:"security_only"`
+}
+
+// Daemon is the configuration of the "daemon" subcommand
+type Daemon struct {
+	Interval Duration `toml:"interval"`
+	Reports  []Report `toml:"report"`
+}
+
+// Webhook is a URL to notify when a package becomes out of date or its provider starts failing
+type Webhook struct {
+	URL         string `toml:"url"`
+	ContentType string `toml:"content_type"`
+	// Template is a text/template for the request body, which is JSON when left empty
+	Template string `toml:"template"`
+	// Retries is how many more times to try after a failure, the default of 3 when left out
+	Retries *int `toml:"retries"`
+}
+
+// Digest is the configuration of the "digest" subcommand
+type Digest struct {
+	From string `toml:"from"`
+	// Fallback receives the packages without a known maintainer, which are skipped when left empty
+	Fallback string `toml:"fallback"`
+	// Maintainers is a TOML file mapping package names to addresses, overriding package.yml
+	Maintainers string `toml:"maintainers"`
+	SMTP        SMTP   `toml:"smtp"`
+}
+
+// SMTP is the server used to send email
+type SMTP struct {
+	Address  string `toml:"address"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+}
+
+// Group is a family of packages that are released together upstream, e.g. KDE Frameworks
+type Group struct {
+	Name     string   `toml:"name"`
+	Packages []string `toml:"packages"`
+	// Match is a regular expression for the upstream location of any other members of the group
+	Match string `toml:"match"`
+}
+
+// Weights are how much each factor counts towards the priority score of a pending update
+type Weights struct {
+	// Magnitude is per level of the change: 3 for a major version, 2 for a minor one and 1 for anything else
+	Magnitude float64 `toml:"magnitude"`
+	// Age is per week since the update was released upstream
+	Age float64 `toml:"age"`
+	// Dependents is per doubling of the packages that depend on it, directly or not
+	Dependents float64 `toml:"dependents"`
+	// Security is per known advisory affecting the packaged version
+	Security float64 `toml:"security"`
+}
+
+// Security is a local vulnerability feed to check the packaged versions against
+type Security struct {
+	// Feed is an NVD JSON feed or a JSON list of OSV entries
+	Feed string `toml:"feed"`
+	// Products maps package names to their "vendor:product" in NVD, or their name in OSV
+	Products map[string]string `toml:"products"`
+}
+
+// Repology is the configuration of the "repology" subcommand
+type Repology struct {
+	// Exclude lists repositories to ignore, such as this distribution itself
+	Exclude []string `toml:"exclude"`
+	// Projects maps package names to Repology projects, when they are not named the same
+	Projects map[string]string `toml:"projects"`
+}
+
+// Provider finds the releases of a project on a page listing all of them, e.g. a directory index
+type Provider struct {
+	Name string `toml:"name"`
+	// Match is a regular expression for the source URLs handled, whose first group (or the whole match)
+	// identifies the project
+	Match string `toml:"match"`
+	// Index is a text/template for the URL of the page listing the releases, given the {{.Name}} of the project
+	Index string `toml:"index"`
+	// Version is a regular expression for every release on the page, whose first group (or one named
+	// "version") is the version. An optional group named "file" is the archive of the release, relative to the page.
+	Version string `toml:"version"`
+}
+
+// Config is the user configuration of this tool
+type Config struct {
+	MaxAge    Duration   `toml:"max_age"`
+	Daemon    Daemon     `toml:"daemon"`
+	Webhooks  []Webhook  `toml:"webhook"`
+	Digest    Digest     `toml:"digest"`
+	Groups    []Group    `toml:"group"`
+	Weights   Weights    `toml:"weights"`
+	Security  Security   `toml:"security"`
+	Repology  Repology   `toml:"repology"`
+	Providers []Provider `toml:"provider"`
+}
+
+// Default is the configuration used for anything missing from the config file
+var Default = Config{
+	MaxAge: Duration{4 * time.Hour},
+	Daemon: Daemon{
+		Interval: Duration{6 * time.Hour},
+	},
+	Repology: Repology{
+		Exclude: []string{"solus"},
+	},
+	Weights: Weights{
+		Magnitude:  10,
+		Age:        1,
+		Dependents: 5,
+		Security:   50,
+	},
+}
+
+// Path gets the location of the config file for the current user
+func Path() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return u.HomeDir + "/.config/ypkg-update-checker.toml", nil
+}
+
+// Load reads the config file of the current user, if it exists
+func Load() (c Config, err error) {
+	c = Default
+	path, err := Path()
+	if err != nil {
+		return
+	}
+	if _, err = os.Stat(path); os.IsNotExist(err) {
+		err = nil
+		return
+	}
+	_, err = toml.DecodeFile(path, &c)
+	return
+}
+
+// LoadMaintainers reads a file mapping package names to maintainer addresses
+func LoadMaintainers(path string) (maintainers map[string]string, err error) {
+	maintainers = make(map[string]string)
+	if path == "" {
+		return
+	}
+	_, err = toml.DecodeFile(path, &maintainers)
+	return
+}
+
+// FindGroup gets a Group by name
+func (c Config) FindGroup(name string) (Group, error) {
+	for _, g := range c.Groups {
+		if g.Name == name {
+			return g, nil
+		}
+	}
+	return Group{}, fmt.Errorf("no group named '%s'", name)
+}
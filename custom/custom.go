@@ -0,0 +1,178 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package custom
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/DataDrake/cuppa/providers"
+	"github.com/DataDrake/cuppa/results"
+	"github.com/DataDrake/ypkg-update-checker/config"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"text/template"
+	"time"
+)
+
+// maxIndexSize limits how much of an index page is read
+const maxIndexSize = 10 << 20
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// Provider finds the releases of a project by searching the page that lists them
+type Provider struct {
+	name    string
+	match   *regexp.Regexp
+	index   *template.Template
+	version *regexp.Regexp
+	// group is the position of the version in a match of version, 0 for the whole match
+	group int
+	// file is the position of the "file" group in version, or -1 without one
+	file int
+}
+
+// New checks and compiles a Provider from the config
+func New(cfg config.Provider) (p *Provider, err error) {
+	if cfg.Name == "" || cfg.Match == "" || cfg.Index == "" || cfg.Version == "" {
+		return nil, errors.New("every provider needs a name, match, index and version")
+	}
+	p = &Provider{
+		name: cfg.Name,
+		file: -1,
+	}
+	if p.match, err = regexp.Compile(cfg.Match); err != nil {
+		return nil, fmt.Errorf("invalid match for '%s': %s", cfg.Name, err.Error())
+	}
+	if p.index, err = template.New(cfg.Name).Parse(cfg.Index); err != nil {
+		return nil, fmt.Errorf("invalid index for '%s': %s", cfg.Name, err.Error())
+	}
+	if p.version, err = regexp.Compile(cfg.Version); err != nil {
+		return nil, fmt.Errorf("invalid version for '%s': %s", cfg.Name, err.Error())
+	}
+	for i, group := range p.version.SubexpNames() {
+		switch {
+		case i == 0:
+		case group == "file":
+			p.file = i
+		case group == "version":
+			p.group = i
+		case p.group == 0:
+			p.group = i
+		}
+	}
+	return p, nil
+}
+
+// Compile creates every Provider in the config
+func Compile(cfgs []config.Provider) ([]providers.Provider, error) {
+	all := make([]providers.Provider, 0)
+	for _, cfg := range cfgs {
+		p, err := New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, p)
+	}
+	return all, nil
+}
+
+// Name gets the name of the Provider
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// Match gets the project of a source URL, or "" if not handled by this Provider
+func (p *Provider) Match(query string) string {
+	found := p.match.FindStringSubmatch(query)
+	switch {
+	case found == nil:
+		return ""
+	case len(found) > 1 && found[1] != "":
+		return found[1]
+	default:
+		return found[0]
+	}
+}
+
+// Latest finds the newest release of a project
+func (p *Provider) Latest(name string) (*results.Result, results.Status) {
+	rs, s := p.Releases(name)
+	if s != results.OK {
+		return nil, s
+	}
+	return rs.Last(), results.OK
+}
+
+// Releases finds every release of a project on its index, oldest first
+func (p *Provider) Releases(name string) (*results.ResultSet, results.Status) {
+	var buff bytes.Buffer
+	if err := p.index.Execute(&buff, struct{ Name string }{name}); err != nil {
+		return nil, results.NotFound
+	}
+	index, err := url.Parse(buff.String())
+	if err != nil {
+		return nil, results.NotFound
+	}
+	resp, err := client.Get(index.String())
+	if err != nil {
+		return nil, results.Unavailable
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, results.NotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, results.Unavailable
+	}
+	page, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxIndexSize))
+	if err != nil {
+		return nil, results.Unavailable
+	}
+	found := make([]*results.Result, 0)
+	seen := make(map[string]bool)
+	for _, m := range p.version.FindAllStringSubmatch(string(page), -1) {
+		version := m[p.group]
+		if version == "" || seen[version] {
+			continue
+		}
+		seen[version] = true
+		location := index.String()
+		if p.file >= 0 && m[p.file] != "" {
+			if file, err := index.Parse(m[p.file]); err == nil {
+				location = file.String()
+			}
+		}
+		found = append(found, results.NewResult(name, version, location, time.Time{}))
+	}
+	if len(found) == 0 {
+		return nil, results.NotFound
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return db.NewVersion(found[j].Version).CompareStrict(db.NewVersion(found[i].Version)) < 0
+	})
+	rs := results.NewResultSet(name)
+	for _, r := range found {
+		rs.AddResult(r)
+	}
+	return rs, results.OK
+}
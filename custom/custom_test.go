@@ -0,0 +1,146 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package custom
+
+import (
+	"github.com/DataDrake/cuppa/results"
+	"github.com/DataDrake/ypkg-update-checker/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// indexPages are served by newTestServer, by path
+var indexPages = map[string]string{
+	// Listed alphabetically, like a directory index
+	"/gnu/foo/": `<a href="foo-1.0.tar.gz">foo-1.0.tar.gz</a>
+<a href="foo-1.1.1.tar.gz">foo-1.1.1.tar.gz</a>
+<a href="foo-1.1.tar.gz">foo-1.1.tar.gz</a>
+<a href="foo-1.1.tar.gz.sig">foo-1.1.tar.gz.sig</a>`,
+	"/gnu/empty/": `<a href="README">README</a>`,
+}
+
+// newTestServer serves indexPages, failing for anything else
+func newTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/gnu/broken/":
+			w.WriteHeader(http.StatusInternalServerError)
+		case indexPages[r.URL.Path] != "":
+			w.Write([]byte(indexPages[r.URL.Path]))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// newTestProvider creates a Provider for the GNU layout of newTestServer with a version pattern
+func newTestProvider(t *testing.T, server *httptest.Server, version string) *Provider {
+	p, err := New(config.Provider{
+		Name:    "GNU",
+		Match:   `^https?://ftp\.gnu\.org/gnu/([^/]+)/`,
+		Index:   server.URL + "/gnu/{{.Name}}/",
+		Version: version,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %s", err)
+	}
+	return p
+}
+
+func TestNew(t *testing.T) {
+	for _, cfg := range []config.Provider{
+		{Name: "Missing", Match: "a", Index: "b"},
+		{Name: "Match", Match: "(", Index: "b", Version: "c"},
+		{Name: "Index", Match: "a", Index: "{{", Version: "c"},
+		{Name: "Version", Match: "a", Index: "b", Version: "("},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("Expected the provider '%s' to be rejected", cfg.Name)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	p := newTestProvider(t, server, `foo-([0-9.]+)\.tar`)
+	if name := p.Match("https://ftp.gnu.org/gnu/foo/foo-1.0.tar.gz"); name != "foo" {
+		t.Errorf("Expected the project 'foo', found '%s'", name)
+	}
+	if name := p.Match("https://example.com/foo/foo-1.0.tar.gz"); name != "" {
+		t.Errorf("Expected no project, found '%s'", name)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	index := server.URL + "/gnu/foo/"
+	tests := []struct {
+		version, latest, location string
+	}{
+		// The first group that is not the file
+		{`href="(?P<file>[^"/]+-([0-9][0-9.]*)\.tar\.(?:xz|gz|bz2))"`, "1.1.1", index + "foo-1.1.1.tar.gz"},
+		// A group named version wins over the first one
+		{`(foo)-(?P<version>[0-9][0-9.]*[0-9])\.tar`, "1.1.1", index},
+		// Without groups, the whole match
+		{`[0-9]+\.[0-9]+\.[0-9]+`, "1.1.1", index},
+		{`foo-([0-9][0-9.]*[0-9])\.tar\.gz"`, "1.1.1", index},
+	}
+	for _, test := range tests {
+		p := newTestProvider(t, server, test.version)
+		r, s := p.Latest("foo")
+		if s != results.OK {
+			t.Errorf("Expected a release for '%s', found status %d", test.version, s)
+			continue
+		}
+		if r.Version != test.latest || r.Location != test.location {
+			t.Errorf("Expected %s at '%s' for '%s', found %s at '%s'", test.latest, test.location, test.version,
+				r.Version, r.Location)
+		}
+	}
+}
+
+func TestReleases(t *testing.T) {
+	server := newTestServer()
+	defer server.Close()
+	p := newTestProvider(t, server, `href="(?P<file>[^"/]+-([0-9][0-9.]*[0-9])\.tar\.gz)"`)
+	rs, s := p.Releases("foo")
+	if s != results.OK {
+		t.Fatalf("Expected releases, found status %d", s)
+	}
+	expected := []string{"1.0", "1.1", "1.1.1"}
+	if rs.Len() != len(expected) {
+		t.Fatalf("Expected %d releases, found %d", len(expected), rs.Len())
+	}
+	for i, version := range expected {
+		if rs.Get(i).Version != version {
+			t.Errorf("Expected release %d to be %s, found %s", i, version, rs.Get(i).Version)
+		}
+	}
+	statuses := map[string]results.Status{
+		"missing": results.NotFound,
+		"empty":   results.NotFound,
+		"broken":  results.Unavailable,
+	}
+	for name, expected := range statuses {
+		if _, s := p.Releases(name); s != expected {
+			t.Errorf("Expected status %d for '%s', found %d", expected, name, s)
+		}
+	}
+}
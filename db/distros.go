@@ -0,0 +1,67 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package db
+
+import (
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+const clearDistrosQuery = "DELETE FROM distros"
+const getAllDistrosQuery = "SELECT * FROM distros ORDER BY package"
+const insertDistroQuery = `
+INSERT INTO distros (package, version, repo, imported)
+VALUES (:package, :version, :repo, :imported)`
+const removeDistrosQuery = "DELETE FROM distros WHERE package IN (?)"
+
+// Distro is the newest version of a package shipped by any other distribution
+type Distro struct {
+	Package string `json:"package"`
+	Version string `json:"version"`
+	// Repo is the distribution that ships Version
+	Repo     string    `json:"repo"`
+	Imported time.Time `json:"imported"`
+}
+
+// SaveDistros replaces every Distro with the ones from a new import
+func SaveDistros(db *sqlx.DB, distros []Distro) error {
+	tx := db.MustBegin()
+	if _, err := tx.Exec(clearDistrosQuery); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, d := range distros {
+		if _, err := tx.NamedExec(insertDistroQuery, d); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetAllDistros gets the version shipped by other distributions for every package, by name
+func GetAllDistros(db *sqlx.DB) (map[string]Distro, error) {
+	list := make([]Distro, 0)
+	if err := db.Select(&list, getAllDistrosQuery); err != nil {
+		return nil, err
+	}
+	distros := make(map[string]Distro)
+	for _, d := range list {
+		distros[d.Package] = d
+	}
+	return distros, nil
+}
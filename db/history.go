@@ -0,0 +1,78 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package db
+
+import (
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+const getHistoryQuery = "SELECT * FROM history WHERE package=? ORDER BY idx, seen"
+const getHistoryBeforeQuery = "SELECT * FROM history WHERE seen <= ? ORDER BY package, idx, seen"
+const insertHistoryQuery = "INSERT INTO history VALUES (:package, :idx, :current, :latest, :status, :seen)"
+const removeHistoryQuery = "DELETE FROM history WHERE package IN (?)"
+
+// Snapshot is a record of the state of a single source at the time it changed
+type Snapshot struct {
+	Package string    `json:"package"`
+	Index   int       `db:"idx" json:"index"`
+	Current string    `json:"current"`
+	Latest  string    `json:"latest"`
+	Status  int       `json:"status"`
+	Seen    time.Time `json:"seen"`
+}
+
+// NewSnapshot records the current state of a Release
+func NewSnapshot(r Release) Snapshot {
+	return Snapshot{
+		Package: r.Package,
+		Index:   r.Index,
+		Current: r.Current,
+		Latest:  r.Latest,
+		Status:  r.Status,
+		Seen:    time.Now(),
+	}
+}
+
+// Changed checks if a Release no longer matches a previous version of itself
+func (r Release) Changed(prev Release) bool {
+	return r.Current != prev.Current || r.Latest != prev.Latest || r.Status != prev.Status
+}
+
+// GetHistory retrieves every recorded Snapshot for a package, oldest first
+func GetHistory(db *sqlx.DB, name string) ([]Snapshot, error) {
+	history := make([]Snapshot, 0)
+	err := db.Select(&history, getHistoryQuery, name)
+	return history, err
+}
+
+// GetHistoryAt retrieves the last Snapshot of every source as of a point in time
+func GetHistoryAt(db *sqlx.DB, t time.Time) (map[string]map[int]Snapshot, error) {
+	history := make([]Snapshot, 0)
+	err := db.Select(&history, getHistoryBeforeQuery, t)
+	if err != nil {
+		return nil, err
+	}
+	state := make(map[string]map[int]Snapshot)
+	for _, snap := range history {
+		if state[snap.Package] == nil {
+			state[snap.Package] = make(map[int]Snapshot)
+		}
+		state[snap.Package][snap.Index] = snap
+	}
+	return state, nil
+}
@@ -0,0 +1,186 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package db
+
+import (
+	"github.com/DataDrake/cuppa/providers"
+	"github.com/DataDrake/cuppa/results"
+	"regexp"
+	"time"
+)
+
+// Monitoring overrides how the upstream releases of a source are found
+type Monitoring struct {
+	// Provider is the name of the only provider to ask, instead of every one matching the source
+	Provider string
+	// ID is the name of the project on the provider, instead of the one matched from the source
+	ID string
+	// VersionRegex must match every version, and its first group becomes the version if it has one
+	VersionRegex *regexp.Regexp
+	// Ignore skips any version matching one of these
+	Ignore []*regexp.Regexp
+	// Series only allows versions starting with these components (e.g. "3.11" or "6.1.x")
+	Series string
+	// Interval replaces MaxAge for this source
+	Interval time.Duration
+}
+
+// providers gets the providers of a Registry to try, in order
+func (m *Monitoring) providers(reg Registry) []providers.Provider {
+	if m == nil || m.Provider == "" {
+		return reg.Providers()
+	}
+	if p := FindProvider(reg, m.Provider); p != nil {
+		return []providers.Provider{p}
+	}
+	return nil
+}
+
+// match gets the name of a source for a provider, or "" if the provider does not handle it
+func (m *Monitoring) match(p providers.Provider, source string) string {
+	if m != nil && m.ID != "" {
+		return m.ID
+	}
+	return p.Match(source)
+}
+
+// filtered checks if only some of the upstream releases may be considered
+func (m *Monitoring) filtered() bool {
+	return m != nil && (m.VersionRegex != nil || len(m.Ignore) > 0 || m.Series != "")
+}
+
+// version cleans up the version of an upstream release, returning "" if it should be skipped
+func (m *Monitoring) version(raw string) string {
+	v := m.clean(raw)
+	if v != "" && m != nil && m.Series != "" && !InSeries(NewVersion(v), NewVersion(m.Series)) {
+		return ""
+	}
+	return v
+}
+
+// clean applies every override but the series to the version of an upstream release
+func (m *Monitoring) clean(raw string) string {
+	if m == nil {
+		return raw
+	}
+	v := raw
+	if m.VersionRegex != nil {
+		found := m.VersionRegex.FindStringSubmatch(raw)
+		if found == nil {
+			return ""
+		}
+		if len(found) > 1 {
+			v = found[1]
+		}
+	}
+	for _, ignore := range m.Ignore {
+		if ignore.MatchString(v) {
+			return ""
+		}
+	}
+	return v
+}
+
+// InSeries checks if a version starts with every component of a series
+func InSeries(v, series Version) bool {
+	if len(v) < len(series) {
+		return false
+	}
+	for i, piece := range series {
+		if v[i] != piece {
+			return false
+		}
+	}
+	return true
+}
+
+// releases gets every upstream release allowed by the Monitoring, with their versions cleaned up
+func (m *Monitoring) releases(p providers.Provider, name string) ([]*results.Result, results.Status) {
+	rs, s := p.Releases(name)
+	if s != results.OK || rs == nil {
+		return nil, s
+	}
+	allowed := make([]*results.Result, 0)
+	for i := 0; i < rs.Len(); i++ {
+		result := *rs.Get(i)
+		if result.Version = m.version(result.Version); result.Version != "" {
+			allowed = append(allowed, &result)
+		}
+	}
+	return allowed, results.OK
+}
+
+// latest finds the newest upstream release allowed by the Monitoring
+func (m *Monitoring) latest(p providers.Provider, name string) (*results.Result, results.Status) {
+	if !m.filtered() {
+		return p.Latest(name)
+	}
+	allowed, s := m.releases(p, name)
+	if s != results.OK {
+		return nil, s
+	}
+	var newest *results.Result
+	for _, result := range allowed {
+		if newest == nil || NewVersion(result.Version).Compare(NewVersion(newest.Version)) < 0 {
+			newest = result
+		}
+	}
+	if newest == nil {
+		return nil, results.NotFound
+	}
+	return newest, results.OK
+}
+
+// countNewer finds how many allowed releases of a project are newer than a version, assuming at least one
+func (m *Monitoring) countNewer(p providers.Provider, name string, v Version) int {
+	allowed, s := m.releases(p, name)
+	if s != results.OK {
+		return 1
+	}
+	count := 0
+	for _, result := range allowed {
+		if NewVersion(result.Version).Compare(v) < 0 {
+			count++
+		}
+	}
+	if count == 0 {
+		return 1
+	}
+	return count
+}
+
+// newerSeries finds the newest upstream version outside of the tracked series, if it is newer than latest
+func (m *Monitoring) newerSeries(p providers.Provider, name string, latest Version) string {
+	if m == nil || m.Series == "" {
+		return ""
+	}
+	rs, s := p.Releases(name)
+	if s != results.OK || rs == nil {
+		return ""
+	}
+	newer := ""
+	for i := 0; i < rs.Len(); i++ {
+		v := m.clean(rs.Get(i).Version)
+		if v == "" || NewVersion(v).Compare(latest) >= 0 {
+			continue
+		}
+		if newer == "" || NewVersion(v).Compare(NewVersion(newer)) < 0 {
+			newer = v
+		}
+	}
+	return newer
+}
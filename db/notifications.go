@@ -0,0 +1,53 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package db
+
+import (
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+const countNotificationsQuery = "SELECT COUNT(*) FROM notifications WHERE package=? AND idx=? AND version=?"
+const insertNotificationQuery = "INSERT INTO notifications VALUES (:package, :idx, :version, :sent)"
+const removeNotificationsQuery = "DELETE FROM notifications WHERE package IN (?)"
+
+// Notification is a record of a new upstream version that has already been announced
+type Notification struct {
+	Package string
+	Index   int `db:"idx"`
+	Version string
+	Sent    time.Time
+}
+
+// Announced checks if a new upstream version of a source has already been announced
+func Announced(db *sqlx.DB, r Release) (bool, error) {
+	var count int
+	err := db.Get(&count, countNotificationsQuery, r.Package, r.Index, r.Latest)
+	return count > 0, err
+}
+
+// MarkAnnounced records that a new upstream version of a source has been announced
+func MarkAnnounced(db *sqlx.DB, r Release) error {
+	n := Notification{
+		Package: r.Package,
+		Index:   r.Index,
+		Version: r.Latest,
+		Sent:    time.Now(),
+	}
+	_, err := db.NamedExec(insertNotificationQuery, n)
+	return err
+}
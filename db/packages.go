@@ -17,31 +17,128 @@
 package db
 
 import (
+	"database/sql/driver"
+	"fmt"
 	"github.com/jmoiron/sqlx"
 	"sort"
+	"strings"
 )
 
-const removePackageQuery = "DROP * FROM releases WHERE package IN (?)"
+const removePackageQuery = "DELETE FROM releases WHERE package IN (?)"
+const removeMetadataQuery = "DELETE FROM packages WHERE name IN (?)"
+const savePackageQuery = `INSERT OR REPLACE INTO packages
+(name, version, release, license, homepage, summary, component, builddeps, rundeps, maintainer, email, lint, source)
+VALUES (:name, :version, :release, :license, :homepage, :summary, :component, :builddeps, :rundeps, :maintainer, :email,
+    :lint, :source)`
+const getPackageQuery = "SELECT * FROM packages WHERE name=?"
+const getAllPackagesQuery = "SELECT * FROM packages"
 
+// List is a list of strings, stored one per line
+type List []string
+
+// Value joins the List for storage
+func (l List) Value() (driver.Value, error) {
+	return strings.Join(l, "\n"), nil
+}
+
+// Scan splits a stored List
+func (l *List) Scan(src interface{}) error {
+	var raw string
+	switch v := src.(type) {
+	case nil:
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into a List", src)
+	}
+	*l = List{}
+	if raw != "" {
+		*l = strings.Split(raw, "\n")
+	}
+	return nil
+}
+
+// Package is the metadata of a package, as found in its package.yml
+type Package struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Release    int    `json:"release"`
+	License    List   `json:"license"`
+	Homepage   string `json:"homepage"`
+	Summary    string `json:"summary"`
+	Component  string `json:"component"`
+	BuildDeps  List   `db:"builddeps" json:"builddeps"`
+	RunDeps    List   `db:"rundeps" json:"rundeps"`
+	Maintainer string `json:"maintainer"`
+	Email      string `json:"email"`
+	// Lint lists the mistakes found in the package.yml
+	Lint List `json:"lint"`
+	// Source is the URL of the first source, as written in the package.yml
+	Source string `json:"source"`
+}
+
+// SavePackage records the latest metadata of a package
+func SavePackage(db *sqlx.DB, p Package) error {
+	_, err := db.NamedExec(savePackageQuery, p)
+	return err
+}
+
+// GetPackage gets the metadata of a single package
+func GetPackage(db *sqlx.DB, name string) (p Package, err error) {
+	err = db.Get(&p, getPackageQuery, name)
+	return
+}
+
+// GetAllPackages gets the metadata of every package, by name
+func GetAllPackages(db *sqlx.DB) (map[string]Package, error) {
+	list := make([]Package, 0)
+	if err := db.Select(&list, getAllPackagesQuery); err != nil {
+		return nil, err
+	}
+	packages := make(map[string]Package)
+	for _, p := range list {
+		packages[p.Name] = p
+	}
+	return packages, nil
+}
+
+// UpdatePackage saves the releases of a package, matching them to the saved ones by Index,
+// and removes any saved release that is no longer there
 func UpdatePackage(db *sqlx.DB, releases []Release) error {
-	prev, err := GetReleases(db, releases[0].Package)
+	saved, err := GetReleases(db, releases[0].Package)
 	if err != nil {
 		return err
 	}
+	prev := make(map[int]Release)
+	for _, release := range saved {
+		prev[release.Index] = release
+	}
 	tx := db.MustBegin()
-	for index, release := range releases {
-		if index >= len(prev) {
-			_, err = tx.NamedExec(insertReleaseQuery, release)
-		} else {
+	for _, release := range releases {
+		old, ok := prev[release.Index]
+		if ok {
 			_, err = tx.NamedExec(updateReleaseQuery, release)
+		} else {
+			_, err = tx.NamedExec(insertReleaseQuery, release)
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		delete(prev, release.Index)
+		if ok && !release.Changed(old) {
+			continue
 		}
+		_, err = tx.NamedExec(insertHistoryQuery, NewSnapshot(release))
 		if err != nil {
 			tx.Rollback()
 			return err
 		}
 	}
-	for i := len(releases); i < len(prev); i++ {
-		_, err := tx.NamedExec(removeReleaseQuery, prev[i])
+	for _, old := range prev {
+		_, err := tx.NamedExec(removeReleaseQuery, old)
 		if err != nil {
 			tx.Rollback()
 			return err
@@ -62,18 +159,24 @@ func CleanPackages(db *sqlx.DB, curr []string) error {
 	sort.Strings(prev)
 	deletions := make([]string, 0)
 	for _, p := range prev {
-		if sort.SearchStrings(curr, p) == len(curr) {
+		if i := sort.SearchStrings(curr, p); i == len(curr) || curr[i] != p {
 			deletions = append(deletions, p)
 		}
 	}
 	if len(deletions) == 0 {
 		return nil
 	}
-	query, args, err := sqlx.In(removePackageQuery, deletions)
-	if err != nil {
-		return err
+	for _, remove := range []string{removePackageQuery, removeMetadataQuery, removeHistoryQuery, removeNotificationsQuery,
+		removeDistrosQuery} {
+		query, args, err := sqlx.In(remove, deletions)
+		if err != nil {
+			return err
+		}
+		query = db.Rebind(query)
+		_, err = db.Exec(query, args...)
+		if err != nil {
+			return err
+		}
 	}
-	query = db.Rebind(query)
-	_, err = db.Exec(query, args)
-	return err
+	return nil
 }
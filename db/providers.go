@@ -0,0 +1,56 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package db
+
+import (
+	"github.com/DataDrake/cuppa/providers"
+	"strings"
+)
+
+// Registry supplies the providers used to find upstream releases, so that they can be replaced for testing
+type Registry interface {
+	// Providers gets every provider to try, in order
+	Providers() []providers.Provider
+}
+
+// ProviderList is a Registry of a fixed list of providers
+type ProviderList []providers.Provider
+
+// Providers gets every provider in the list
+func (l ProviderList) Providers() []providers.Provider {
+	return l
+}
+
+// FindProvider gets the provider of a Registry with a name, ignoring case, or nil if there is none
+func FindProvider(reg Registry, name string) providers.Provider {
+	for _, p := range reg.Providers() {
+		if strings.EqualFold(p.Name(), name) {
+			return p
+		}
+	}
+	return nil
+}
+
+// CuppaRegistry is the Registry of every provider built into cuppa, followed by any custom ones
+type CuppaRegistry struct {
+	Custom []providers.Provider
+}
+
+// Providers gets the providers of cuppa, followed by the custom ones
+func (c CuppaRegistry) Providers() []providers.Provider {
+	return append(append([]providers.Provider{}, providers.All()...), c.Custom...)
+}
@@ -17,14 +17,15 @@
 package db
 
 import (
-    "fmt"
-	"github.com/DataDrake/cuppa/providers"
+	"fmt"
 	"github.com/DataDrake/cuppa/results"
+	"github.com/DataDrake/ypkg-update-checker/github"
 	"github.com/jmoiron/sqlx"
 	"time"
 )
 
 const (
+	StatusFailed     = -5
 	StatusMissingYML = -4
 	StatusUnmatched  = -3
 	StatusOutOfDate  = -2
@@ -33,9 +34,24 @@ const (
 	StatusAhead      = 1
 )
 
+// StatusNames are human-readable descriptions of each Status
+var StatusNames = map[int]string{
+	StatusFailed:     "Provider Failed",
+	StatusMissingYML: "Missing package.yml",
+	StatusUnmatched:  "Unmatched",
+	StatusOutOfDate:  "Out of Date",
+	StatusHeldBack:   "Held Back",
+	StatusUpToDate:   "Up to Date",
+	StatusAhead:      "Newer than Upstream",
+}
+
 const getReleasesQuery = "SELECT * FROM releases WHERE package=? ORDER BY idx"
 const getAllReleasesQuery = "SELECT * FROM releases ORDER BY package, idx"
-const insertReleaseQuery = "INSERT INTO releases VALUES (:package, :source, :current, :latest, :updated, :status, :idx)"
+const insertReleaseQuery = `
+INSERT INTO releases (package, source, current, latest, updated, status, idx, provider, available, behind, ref, newer, series, auxiliary,
+    advisories, score, cves, src_idx)
+VALUES (:package, :source, :current, :latest, :updated, :status, :idx, :provider, :available, :behind, :ref, :newer, :series,
+    :auxiliary, :advisories, :score, :cves, :src_idx)`
 const updateReleaseQuery = `
 UPDATE releases
 SET
@@ -43,18 +59,73 @@ SET
     current=:current,
     latest=:latest,
     updated=:updated,
-    status=:status
+    status=:status,
+    provider=:provider,
+    available=:available,
+    behind=:behind,
+    ref=:ref,
+    newer=:newer,
+    series=:series,
+    auxiliary=:auxiliary,
+    advisories=:advisories,
+    score=:score,
+    cves=:cves,
+    src_idx=:src_idx
 WHERE package=:package AND idx=:idx`
-const removeReleaseQuery = "DROP * FROM releases WHERE package=:package AND index=:idx"
+const removeReleaseQuery = "DELETE FROM releases WHERE package=:package AND idx=:idx"
+const saveScoreQuery = "UPDATE releases SET score=:score WHERE package=:package AND idx=:idx"
 
 type Release struct {
-	Package string
-	Source  string
-	Current string
-	Latest  string
-	Updated time.Time
-	Status  int
-	Index   int `db:"idx"`
+	Package  string    `json:"package"`
+	Source   string    `json:"source"`
+	Current  string    `json:"current"`
+	Latest   string    `json:"latest"`
+	Updated  time.Time `json:"updated"`
+	Status   int       `json:"status"`
+	Index    int       `db:"idx" json:"index"`
+	Provider string    `json:"provider"`
+
+	// When Latest was released, or first seen if the provider does not say
+	Available time.Time `json:"available"`
+	// Number of upstream releases newer than Current
+	Behind int `json:"behind"`
+	// Tag or commit of a git source, empty for tarballs
+	Ref string `json:"ref"`
+	// Newest upstream version outside of the tracked series, if newer than Latest
+	Newer string `json:"newer"`
+	// Series tracked by this row, when monitoring.yml tracks more than one of the same source
+	Series string `json:"series"`
+	// Any source after the first, e.g. patches or data, with Current taken from its own URL
+	Auxiliary bool `json:"auxiliary"`
+	// Number of known security advisories affecting Current
+	Advisories int `json:"advisories"`
+	// IDs of the known security advisories affecting Current
+	CVEs List `db:"cves" json:"cves"`
+	// Priority of packaging Latest, higher is more urgent
+	Score float64 `json:"score"`
+	// Position of the source in package.yml, or -1 if saved before it was recorded
+	SourceIndex int `db:"src_idx" json:"source_index"`
+
+	// Overrides from monitoring.yml, which are not stored
+	Monitoring *Monitoring `db:"-" json:"-"`
+}
+
+// TimeBehind is how long a newer version has been available upstream
+func (r Release) TimeBehind() time.Duration {
+	if r.Status != StatusOutOfDate || r.Available.IsZero() {
+		return 0
+	}
+	return time.Since(r.Available)
+}
+
+// Tracks checks if a Release is the row of a source of its package for a series
+func (r Release) Tracks(source int, series string) bool {
+	saved := r.SourceIndex
+	if saved < 0 {
+		// Every source had a single row back then
+		saved = r.Index
+	}
+	return saved == source && r.Series == series
 }
 
 func GetReleases(db *sqlx.DB, name string) ([]Release, error) {
@@ -69,31 +140,83 @@ func GetAllReleases(db *sqlx.DB) ([]Release, error) {
 	return releases, err
 }
 
-func (r Release) Check(db *sqlx.DB) Release {
-	if r.Status < StatusOutOfDate || time.Since(r.Updated) > (4*time.Hour) {
-        fmt.Printf("Updating %s...\n", r.Package)
+// SaveScores records the priority of every Release, leaving everything else as it is
+func SaveScores(db *sqlx.DB, releases []Release) error {
+	tx := db.MustBegin()
+	for _, release := range releases {
+		if _, err := tx.NamedExec(saveScoreQuery, release); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// MaxAge is how long the latest release of a matched source is trusted before it is checked again
+var MaxAge = 4 * time.Hour
+
+// Stale checks if a Release needs to be looked up again
+func (r Release) Stale() bool {
+	maxAge := MaxAge
+	if r.Monitoring != nil && r.Monitoring.Interval > 0 {
+		maxAge = r.Monitoring.Interval
+	}
+	return r.Status < StatusOutOfDate || time.Since(r.Updated) > maxAge
+}
+
+// packaged gets the version to compare against upstream, which is the tag for git sources
+func (r Release) packaged() string {
+	if r.Ref != "" {
+		return r.Ref
+	}
+	return r.Current
+}
+
+// Check looks up the latest release with the providers of a Registry, if the last lookup is stale
+func (r Release) Check(reg Registry, run *Run) Release {
+	if r.Stale() {
+		fmt.Printf("Updating %s...\n", r.Package)
+		run.Check()
+		if github.IsCommit(r.Ref) {
+			return r.checkCommit(run)
+		}
 		found := false
-		for _, p := range providers.All() {
-			name := p.Match(r.Source)
+		failed := false
+		for _, p := range r.Monitoring.providers(reg) {
+			name := r.Monitoring.match(p, r.Source)
 			if name == "" {
 				continue
 			}
-			result, s := p.Latest(name)
+			result, s := r.Monitoring.latest(p, name)
 			if s != results.OK || result == nil {
+				if s == results.Unavailable {
+					failed = true
+					run.ProviderFailed(p.Name())
+				}
 				continue
 			}
 			found = true
+			r.Provider = p.Name()
+			if result.Version != r.Latest || r.Available.IsZero() {
+				r.Available = result.Published
+				if r.Available.IsZero() {
+					r.Available = time.Now()
+				}
+			}
 			r.Latest = result.Version
 			r.Source = result.Location
 			r.Updated = time.Now()
+			r.Newer = r.Monitoring.newerSeries(p, name, NewVersion(r.Latest))
 			if r.Status == StatusHeldBack {
 				return r
 			}
-			vOld := NewVersion(r.Current)
+			vOld := NewVersion(r.packaged())
 			vLatest := NewVersion(r.Latest)
 			compare := vLatest.Compare(vOld)
+			r.Behind = 0
 			if compare < 0 {
 				r.Status = StatusOutOfDate
+				r.Behind = r.Monitoring.countNewer(p, name, vOld)
 			} else if compare == 0 {
 				r.Status = StatusUpToDate
 			} else {
@@ -101,8 +224,62 @@ func (r Release) Check(db *sqlx.DB) Release {
 			}
 		}
 		if !found {
-			r.Status = StatusUnmatched
+			r.Provider = ""
+			r.Newer = ""
+			r.Updated = time.Now()
+			if failed {
+				r.Status = StatusFailed
+			} else {
+				r.Status = StatusUnmatched
+			}
+		}
+	} else {
+		run.Skip()
+	}
+	return r
+}
+
+// checkCommit compares a git source pinned to a commit against the head of the default branch
+func (r Release) checkCommit(run *Run) Release {
+	r.Updated = time.Now()
+	repo := github.Match(r.Source)
+	if repo == "" {
+		r.Provider = ""
+		r.Status = StatusUnmatched
+		return r
+	}
+	pinned, err := github.GetCommit(repo, r.Ref)
+	var head github.Commit
+	if err == nil {
+		head, err = github.Head(repo)
+	}
+	if err != nil {
+		fmt.Printf("Failed to check %s, reason: %s\n", r.Package, err.Error())
+		run.ProviderFailed(github.Name)
+		r.Provider = ""
+		r.Status = StatusFailed
+		return r
+	}
+	r.Provider = github.Name
+	r.Latest = head.SHA
+	if len(r.Latest) > 7 {
+		r.Latest = r.Latest[:7]
+	}
+	r.Available = head.Date
+	r.Behind = 0
+	if r.Status == StatusHeldBack {
+		return r
+	}
+	switch {
+	case head.SHA == pinned.SHA:
+		r.Status = StatusUpToDate
+	case head.Date.After(pinned.Date):
+		r.Status = StatusOutOfDate
+		if r.Behind, err = github.CountBetween(repo, pinned.SHA, head.SHA); err != nil || r.Behind == 0 {
+			r.Behind = 1
 		}
+	default:
+		r.Status = StatusAhead
 	}
 	return r
 }
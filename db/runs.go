@@ -0,0 +1,238 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package db
+
+import (
+	"github.com/jmoiron/sqlx"
+	"sort"
+	"sync"
+	"time"
+)
+
+const getRunQuery = "SELECT * FROM runs WHERE id=?"
+const getAllRunsQuery = "SELECT * FROM runs ORDER BY id"
+const insertRunQuery = "INSERT INTO runs (started, finished, version) VALUES (:started, :finished, :version)"
+const finishRunQuery = `
+UPDATE runs
+SET
+    finished=:finished,
+    packages=:packages,
+    checked=:checked,
+    skipped=:skipped,
+    sources=:sources,
+    outofdate=:outofdate,
+    unmatched=:unmatched,
+    behind=:behind
+WHERE id=:id`
+const getRunProvidersQuery = "SELECT * FROM run_providers ORDER BY run, provider"
+const insertRunProviderQuery = "INSERT INTO run_providers (run, provider, errors, matches) VALUES (:run, :provider, :errors, :matches)"
+const getOutOfDateHistoryQuery = `
+SELECT history.* FROM history
+JOIN releases ON history.package=releases.package AND history.idx=releases.idx AND history.latest=releases.latest
+WHERE releases.status=?
+ORDER BY history.package, history.idx, history.seen`
+
+// Run is a record of a single invocation of "update", a zero Run may be used to count checks that
+// should not be recorded
+type Run struct {
+	ID       int
+	Started  time.Time
+	Finished time.Time
+	Packages int
+	Checked  int
+	Skipped  int
+	Version  string
+
+	// Summary of the releases once the Run has finished
+	Sources   int
+	OutOfDate int
+	Unmatched int
+	Behind    time.Duration // median time that out-of-date sources have been behind upstream
+
+	lock    sync.Mutex
+	errors  map[string]int
+	matches map[string]int
+}
+
+// RunProvider is a summary of how a single provider behaved during a Run
+type RunProvider struct {
+	Run      int
+	Provider string
+	Errors   int
+	Matches  int
+}
+
+// NewRun records the start of a new Run
+func NewRun(db *sqlx.DB, version string) (*Run, error) {
+	now := time.Now()
+	run := &Run{
+		Started:  now,
+		Finished: now,
+		Version:  version,
+		errors:   make(map[string]int),
+		matches:  make(map[string]int),
+	}
+	result, err := db.NamedExec(insertRunQuery, run)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	run.ID = int(id)
+	return run, err
+}
+
+// Check counts a source that was looked up with the providers
+func (run *Run) Check() {
+	run.lock.Lock()
+	run.Checked++
+	run.lock.Unlock()
+}
+
+// Skip counts a source that was recently checked and did not need to be looked up
+func (run *Run) Skip() {
+	run.lock.Lock()
+	run.Skipped++
+	run.lock.Unlock()
+}
+
+// ProviderFailed counts an error returned by a provider
+func (run *Run) ProviderFailed(provider string) {
+	run.lock.Lock()
+	if run.errors == nil {
+		run.errors = make(map[string]int)
+	}
+	run.errors[provider]++
+	run.lock.Unlock()
+}
+
+// Finish records the end of a Run and everything it counted
+func (run *Run) Finish(db *sqlx.DB, packages int) error {
+	run.lock.Lock()
+	defer run.lock.Unlock()
+	run.Finished = time.Now()
+	run.Packages = packages
+	err := run.summarize(db)
+	if err != nil {
+		return err
+	}
+	tx := db.MustBegin()
+	_, err = tx.NamedExec(finishRunQuery, run)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	providers := make(map[string]bool)
+	for provider := range run.errors {
+		providers[provider] = true
+	}
+	for provider := range run.matches {
+		providers[provider] = true
+	}
+	for provider := range providers {
+		p := RunProvider{run.ID, provider, run.errors[provider], run.matches[provider]}
+		_, err = tx.NamedExec(insertRunProviderQuery, p)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// summarize counts the state of every release at the end of a Run
+func (run *Run) summarize(db *sqlx.DB) error {
+	releases, err := GetAllReleases(db)
+	if err != nil {
+		return err
+	}
+	run.Sources = len(releases)
+	for _, r := range releases {
+		if r.Provider != "" {
+			run.matches[r.Provider]++
+		}
+		if r.Auxiliary {
+			// Extra sources, e.g. patches, are not counted against the package
+			continue
+		}
+		switch r.Status {
+		case StatusOutOfDate:
+			run.OutOfDate++
+		case StatusUnmatched:
+			run.Unmatched++
+		}
+	}
+	history := make([]Snapshot, 0)
+	err = db.Select(&history, getOutOfDateHistoryQuery, StatusOutOfDate)
+	if err != nil {
+		return err
+	}
+	available := make(map[string]map[int]time.Time)
+	for _, snap := range history {
+		if available[snap.Package] == nil {
+			available[snap.Package] = make(map[int]time.Time)
+		}
+		if _, ok := available[snap.Package][snap.Index]; !ok {
+			available[snap.Package][snap.Index] = snap.Seen
+		}
+	}
+	behind := make([]time.Duration, 0)
+	for _, r := range releases {
+		if r.Status != StatusOutOfDate || r.Auxiliary {
+			continue
+		}
+		since := r.Available
+		if since.IsZero() {
+			since = available[r.Package][r.Index]
+		}
+		if !since.IsZero() {
+			behind = append(behind, run.Finished.Sub(since))
+		}
+	}
+	if len(behind) > 0 {
+		sort.Slice(behind, func(i, j int) bool { return behind[i] < behind[j] })
+		run.Behind = behind[len(behind)/2]
+	}
+	return nil
+}
+
+// GetRun retrieves a single Run by its ID
+func GetRun(db *sqlx.DB, id int) (*Run, error) {
+	run := &Run{}
+	err := db.Get(run, getRunQuery, id)
+	return run, err
+}
+
+// GetAllRuns retrieves every Run, oldest first
+func GetAllRuns(db *sqlx.DB) ([]*Run, error) {
+	runs := make([]*Run, 0)
+	err := db.Select(&runs, getAllRunsQuery)
+	return runs, err
+}
+
+// GetRunProviders retrieves the provider summaries of every Run, indexed by Run ID
+func GetRunProviders(db *sqlx.DB) (map[int][]RunProvider, error) {
+	all := make([]RunProvider, 0)
+	err := db.Select(&all, getRunProvidersQuery)
+	if err != nil {
+		return nil, err
+	}
+	providers := make(map[int][]RunProvider)
+	for _, p := range all {
+		providers[p.Run] = append(providers[p.Run], p)
+	}
+	return providers, nil
+}
@@ -22,6 +22,7 @@ import (
 )
 
 const getTablesQuery = "SELECT name FROM sqlite_master WHERE type='table'"
+const hasColumnQuery = "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name=?"
 
 const releaseSchema = `
 CREATE TABLE releases (
@@ -31,28 +32,174 @@ CREATE TABLE releases (
     latest TEXT,
     updated DATETIME,
     status INTEGER,
-    idx  INTEGER
+    idx  INTEGER,
+    provider TEXT DEFAULT '',
+    available DATETIME DEFAULT '0001-01-01 00:00:00',
+    behind INTEGER DEFAULT 0,
+    ref TEXT DEFAULT '',
+    newer TEXT DEFAULT '',
+    series TEXT DEFAULT '',
+    auxiliary INTEGER DEFAULT 0,
+    advisories INTEGER DEFAULT 0,
+    score REAL DEFAULT 0,
+    cves TEXT DEFAULT '',
+    src_idx INTEGER DEFAULT -1
 );
 `
 
+const historySchema = `
+CREATE TABLE history (
+    package TEXT,
+    idx INTEGER,
+    current TEXT,
+    latest TEXT,
+    status INTEGER,
+    seen DATETIME
+);
+INSERT INTO history SELECT package, idx, current, latest, status, updated FROM releases;
+`
+
+const runSchema = `
+CREATE TABLE runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    started DATETIME,
+    finished DATETIME,
+    packages INTEGER DEFAULT 0,
+    checked INTEGER DEFAULT 0,
+    skipped INTEGER DEFAULT 0,
+    version TEXT DEFAULT '',
+    sources INTEGER DEFAULT 0,
+    outofdate INTEGER DEFAULT 0,
+    unmatched INTEGER DEFAULT 0,
+    behind INTEGER DEFAULT 0
+);
+`
+
+const runProviderSchema = `
+CREATE TABLE run_providers (
+    run INTEGER,
+    provider TEXT,
+    errors INTEGER,
+    matches INTEGER DEFAULT 0
+);
+`
+
+const notificationSchema = `
+CREATE TABLE notifications (
+    package TEXT,
+    idx INTEGER,
+    version TEXT,
+    sent DATETIME
+);
+`
+
+const packageSchema = `
+CREATE TABLE packages (
+    name TEXT PRIMARY KEY,
+    version TEXT,
+    release INTEGER,
+    license TEXT,
+    homepage TEXT,
+    summary TEXT,
+    component TEXT,
+    builddeps TEXT,
+    rundeps TEXT,
+    maintainer TEXT,
+    email TEXT,
+    lint TEXT DEFAULT '',
+    source TEXT DEFAULT ''
+);
+`
+
+const distroSchema = `
+CREATE TABLE distros (
+    package TEXT PRIMARY KEY,
+    version TEXT,
+    repo TEXT,
+    imported DATETIME
+);
+`
+
+// tables must be listed in the order they should be created
+var tables = []struct {
+	name   string
+	schema string
+}{
+	{"releases", releaseSchema},
+	{"history", historySchema},
+	{"runs", runSchema},
+	{"run_providers", runProviderSchema},
+	{"notifications", notificationSchema},
+	{"packages", packageSchema},
+	{"distros", distroSchema},
+}
+
+// columns lists every column added to a table after it was first created,
+// so that databases from older versions can be upgraded in place
+var columns = []struct {
+	table      string
+	name       string
+	definition string
+}{
+	{"runs", "packages", "INTEGER DEFAULT 0"},
+	{"runs", "checked", "INTEGER DEFAULT 0"},
+	{"runs", "skipped", "INTEGER DEFAULT 0"},
+	{"runs", "version", "TEXT DEFAULT ''"},
+	{"releases", "provider", "TEXT DEFAULT ''"},
+	{"runs", "sources", "INTEGER DEFAULT 0"},
+	{"runs", "outofdate", "INTEGER DEFAULT 0"},
+	{"runs", "unmatched", "INTEGER DEFAULT 0"},
+	{"runs", "behind", "INTEGER DEFAULT 0"},
+	{"run_providers", "matches", "INTEGER DEFAULT 0"},
+	{"releases", "available", "DATETIME DEFAULT '0001-01-01 00:00:00'"},
+	{"releases", "behind", "INTEGER DEFAULT 0"},
+	{"releases", "ref", "TEXT DEFAULT ''"},
+	{"releases", "newer", "TEXT DEFAULT ''"},
+	{"releases", "series", "TEXT DEFAULT ''"},
+	{"releases", "auxiliary", "INTEGER DEFAULT 0"},
+	{"packages", "lint", "TEXT DEFAULT ''"},
+	{"packages", "source", "TEXT DEFAULT ''"},
+	{"releases", "advisories", "INTEGER DEFAULT 0"},
+	{"releases", "score", "REAL DEFAULT 0"},
+	{"releases", "cves", "TEXT DEFAULT ''"},
+	{"releases", "src_idx", "INTEGER DEFAULT -1"},
+}
+
 func CreateTables(db *sqlx.DB) error {
 	found, err := db.Queryx(getTablesQuery)
 	if err != nil {
 		return err
 	}
-	missing := true
+	existing := make(map[string]bool)
 	for found.Next() {
 		var table string
 		err = found.Scan(&table)
 		if err != nil {
+			found.Close()
 			return err
 		}
-		if table == "releases" {
-			missing = false
+		existing[table] = true
+	}
+	found.Close()
+	for _, table := range tables {
+		if existing[table.name] {
+			continue
+		}
+		_, err := db.Exec(table.schema)
+		if err != nil {
+			return err
 		}
 	}
-	if missing {
-		_, err := db.Exec(releaseSchema)
+	for _, column := range columns {
+		var count int
+		err = db.Get(&count, hasColumnQuery, column.table, column.name)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		_, err = db.Exec("ALTER TABLE " + column.table + " ADD COLUMN " + column.name + " " + column.definition)
 		if err != nil {
 			return err
 		}
@@ -65,10 +212,19 @@ func Open() (db *sqlx.DB, err error) {
 	if err != nil {
 		return
 	}
-	db, err = sqlx.Connect("sqlite3", u.HomeDir+"/.cache/ypkg-update.db")
+	return Connect(u.HomeDir + "/.cache/ypkg-update.db")
+}
+
+// Connect opens any SQLite database (e.g. ":memory:"), creating or upgrading its tables
+func Connect(dsn string) (db *sqlx.DB, err error) {
+	db, err = sqlx.Connect("sqlite3", dsn)
 	if err != nil {
 		return
 	}
+	if dsn == ":memory:" {
+		// Every connection would get an empty database of its own
+		db.SetMaxOpenConns(1)
+	}
 	err = CreateTables(db)
 	return
 }
@@ -97,3 +97,27 @@ func (v Version) Compare(old Version) int {
 	}
 	return result
 }
+
+// CompareStrict is like Compare, but every piece counts, so a version missing a piece is older
+// (e.g. "1.1" is older than "1.1.1"). Like Compare, it is negative when v is newer than old.
+func (v Version) CompareStrict(old Version) int {
+	for i := 0; i < len(v) || i < len(old); i++ {
+		switch {
+		case i == len(v):
+			return 1
+		case i == len(old):
+			return -1
+		case v[i] == old[i]:
+			continue
+		}
+		curr, errCurr := strconv.Atoi(v[i])
+		prev, errPrev := strconv.Atoi(old[i])
+		if errCurr != nil || errPrev != nil {
+			return strings.Compare(old[i], v[i])
+		}
+		if prev != curr {
+			return prev - curr
+		}
+	}
+	return 0
+}
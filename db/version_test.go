@@ -0,0 +1,46 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package db
+
+import (
+	"testing"
+)
+
+func TestCompareStrict(t *testing.T) {
+	tests := []struct {
+		v, old string
+		sign   int
+	}{
+		{"1.0", "1.0", 0},
+		{"v1.0", "1.0", 0},
+		{"1.1", "1.0", -1},
+		{"1.0", "1.1", 1},
+		{"1.10", "1.9", -1},
+		{"1.1.1", "1.1", -1},
+		{"1.1", "1.1.1", 1},
+		{"1.0.2", "1.0", -1},
+		{"2.4", "2.4.1", 1},
+		{"1.0b", "1.0a", -1},
+		{"1.0a", "1.0b", 1},
+	}
+	for _, test := range tests {
+		result := NewVersion(test.v).CompareStrict(NewVersion(test.old))
+		if (result < 0 && test.sign >= 0) || (result > 0 && test.sign <= 0) || (result == 0 && test.sign != 0) {
+			t.Errorf("Expected '%s' compared to '%s' to have sign %d, found %d", test.v, test.old, test.sign, result)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/DataDrake/ypkg-update-checker/config"
+	"github.com/DataDrake/ypkg-update-checker/pkg"
+	"io/ioutil"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DigestRow is the format string for a single package in the body of a Digest
+const DigestRow = "    %-30s %-15s -> %-15s %s\n"
+
+// Digest is the list of newly out-of-date packages for a single maintainer
+type Digest struct {
+	To      *mail.Address
+	Since   time.Time
+	Changes []pkg.Change
+}
+
+// Group splits the changes by maintainer, as found by the lookup function
+func Group(since time.Time, changes []pkg.Change, lookup func(name string) *mail.Address) []*Digest {
+	digests := make(map[string]*Digest)
+	for _, change := range changes {
+		to := lookup(change.Release.Package)
+		if to == nil {
+			continue
+		}
+		d := digests[to.Address]
+		if d == nil {
+			d = &Digest{To: to, Since: since}
+			digests[to.Address] = d
+		}
+		d.Changes = append(d.Changes, change)
+	}
+	all := make([]*Digest, 0)
+	for _, d := range digests {
+		all = append(all, d)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].To.Address < all[j].To.Address })
+	return all
+}
+
+// Subject summarizes the Digest
+func (d Digest) Subject() string {
+	if len(d.Changes) == 1 {
+		return "1 package became out of date"
+	}
+	return fmt.Sprintf("%d packages became out of date", len(d.Changes))
+}
+
+// Message generates the full email for a Digest, with Unix line endings
+func (d Digest) Message(from *mail.Address) []byte {
+	var msg bytes.Buffer
+	now := time.Now()
+	fmt.Fprintf(&msg, "From: %s\n", from)
+	fmt.Fprintf(&msg, "To: %s\n", d.To)
+	fmt.Fprintf(&msg, "Subject: %s\n", d.Subject())
+	fmt.Fprintf(&msg, "Date: %s\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%d.%s>\n", now.UnixNano(), strings.Replace(d.To.Address, "@", ".", -1)+"@ypkg-update-checker")
+	msg.WriteString("MIME-Version: 1.0\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\n\n")
+	fmt.Fprintf(&msg, "The following packages became out of date since %s:\n\n", d.Since.Format(pkg.HistoryTimeFormat))
+	fmt.Fprintf(&msg, DigestRow, "Package", "Packaged", "Latest", "Location")
+	for _, change := range d.Changes {
+		r := change.Release
+		fmt.Fprintf(&msg, DigestRow, r.Package, r.Current, r.Latest, r.Source)
+	}
+	return msg.Bytes()
+}
+
+// Send delivers every Digest through an SMTP server
+func Send(digests []*Digest, from *mail.Address, server config.SMTP) error {
+	var auth smtp.Auth
+	if server.Username != "" {
+		host, _, err := net.SplitHostPort(server.Address)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", server.Username, server.Password, host)
+	}
+	for _, d := range digests {
+		// SMTP requires CRLF line endings
+		msg := bytes.Replace(d.Message(from), []byte("\n"), []byte("\r\n"), -1)
+		err := smtp.SendMail(server.Address, auth, from.Address, []string{d.To.Address}, msg)
+		if err != nil {
+			return fmt.Errorf("failed to send to '%s': %s", d.To.Address, err.Error())
+		}
+	}
+	return nil
+}
+
+// WriteEML saves every Digest as a separate .eml file in a directory
+func WriteEML(digests []*Digest, from *mail.Address, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, d := range digests {
+		path := filepath.Join(dir, d.To.Address+".eml")
+		if err := ioutil.WriteFile(path, d.Message(from), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMbox appends every Digest to a single mbox file
+func WriteMbox(digests []*Digest, from *mail.Address, path string) error {
+	var buff bytes.Buffer
+	for _, d := range digests {
+		fmt.Fprintf(&buff, "From %s %s\n", from.Address, time.Now().Format(time.ANSIC))
+		for _, line := range strings.Split(string(d.Message(from)), "\n") {
+			// Escape lines that would otherwise start a new message
+			if strings.HasPrefix(strings.TrimLeft(line, ">"), "From ") {
+				line = ">" + line
+			}
+			fmt.Fprintln(&buff, line)
+		}
+	}
+	mbox, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = buff.WriteTo(mbox); err != nil {
+		mbox.Close()
+		return err
+	}
+	return mbox.Close()
+}
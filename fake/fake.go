@@ -0,0 +1,136 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package fake
+
+import (
+	"encoding/json"
+	"github.com/DataDrake/cuppa/results"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Release is a single upstream release of a fixture
+type Release struct {
+	Version   string    `json:"version"`
+	Location  string    `json:"location"`
+	Published time.Time `json:"published"`
+}
+
+// Project is the fixture data for a single upstream project
+type Project struct {
+	// Sources are prefixes of the source URLs that belong to the project
+	Sources []string `json:"sources"`
+	// Releases are listed oldest first
+	Releases []Release `json:"releases"`
+	// Unavailable fails every lookup, as if the provider were down
+	Unavailable bool `json:"unavailable"`
+}
+
+// Provider answers lookups from fixture data instead of the network, and counts them
+type Provider struct {
+	name     string
+	projects map[string]Project
+	lock     sync.Mutex
+	lookups  map[string]int
+}
+
+// New creates a Provider for a set of projects, by name
+func New(name string, projects map[string]Project) *Provider {
+	return &Provider{
+		name:     name,
+		projects: projects,
+		lookups:  make(map[string]int),
+	}
+}
+
+// Load reads a Provider from a JSON file of its name and projects
+func Load(path string) (*Provider, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixture struct {
+		Name     string             `json:"name"`
+		Projects map[string]Project `json:"projects"`
+	}
+	if err = json.Unmarshal(raw, &fixture); err != nil {
+		return nil, err
+	}
+	return New(fixture.Name, fixture.Projects), nil
+}
+
+// Lookups gets how many times the releases of a project have been asked for
+func (p *Provider) Lookups(name string) int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.lookups[name]
+}
+
+// Name gets the name of the Provider
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// Match gets the project with a source prefix matching a URL, or "" if there is none
+func (p *Provider) Match(query string) string {
+	names := make([]string, 0)
+	for name := range p.projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, prefix := range p.projects[name].Sources {
+			if strings.HasPrefix(query, prefix) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// Latest gets the newest release of a project
+func (p *Provider) Latest(name string) (*results.Result, results.Status) {
+	rs, s := p.Releases(name)
+	if s != results.OK {
+		return nil, s
+	}
+	return rs.Last(), results.OK
+}
+
+// Releases gets every release of a project, oldest first
+func (p *Provider) Releases(name string) (*results.ResultSet, results.Status) {
+	p.lock.Lock()
+	p.lookups[name]++
+	p.lock.Unlock()
+	project, ok := p.projects[name]
+	switch {
+	case !ok:
+		return nil, results.NotFound
+	case project.Unavailable:
+		return nil, results.Unavailable
+	case len(project.Releases) == 0:
+		return nil, results.NotFound
+	}
+	rs := results.NewResultSet(name)
+	for _, r := range project.Releases {
+		rs.AddResult(results.NewResult(name, r.Version, r.Location, r.Published))
+	}
+	return rs, results.OK
+}
@@ -0,0 +1,52 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Repo is a git working tree, operated on with the git command
+type Repo struct {
+	Dir string
+}
+
+// run carries out a single git command in the working tree
+func (r Repo) run(args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// CreateBranch starts a new branch from the current commit, keeping any changes to the working tree
+func (r Repo) CreateBranch(name string) error {
+	return r.run("checkout", "-b", name)
+}
+
+// Commit records the changes to some files, and only those, even if others are staged
+func (r Repo) Commit(message string, paths ...string) error {
+	if err := r.run(append([]string{"add", "--"}, paths...)...); err != nil {
+		return err
+	}
+	return r.run(append([]string{"commit", "-m", message, "--"}, paths...)...)
+}
@@ -0,0 +1,68 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommitOnlyPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	repo := Repo{Dir: dir}
+	write := func(name, content string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = repo.run("init", "-q"); err != nil {
+		t.Skipf("No usable git: %s", err)
+	}
+	repo.run("config", "user.name", "Test")
+	repo.run("config", "user.email", "test@example.com")
+	write("package.yml", "version : 1.0\n")
+	write("notes.txt", "old\n")
+	if err = repo.Commit("Initial", "package.yml", "notes.txt"); err != nil {
+		t.Fatalf("Failed to commit: %s", err)
+	}
+	// Staged by the packager beforehand, so not part of the update
+	write("notes.txt", "new\n")
+	if err = repo.run("add", "notes.txt"); err != nil {
+		t.Fatal(err)
+	}
+	write("package.yml", "version : 1.1\n")
+	if err = repo.Commit("foo: Update to 1.1", "package.yml"); err != nil {
+		t.Fatalf("Failed to commit: %s", err)
+	}
+	cmd := exec.Command("git", "show", "--name-only", "--format=", "HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files := strings.Fields(string(out)); len(files) != 1 || files[0] != "package.yml" {
+		t.Errorf("Expected only package.yml to be committed, found %v", files)
+	}
+}
@@ -0,0 +1,136 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Name identifies this package as the provider of a release
+const Name = "GitHub Commits"
+
+// APIBase is the root of the GitHub REST API
+var APIBase = "https://api.github.com"
+
+// repoRegex matches the owner and name of a repository in its clone URL
+var repoRegex = regexp.MustCompile(`^(?:https?|git)://github\.com/([^/]+)/([^/]+?)(?:\.git)?/?$`)
+
+// projectRegex matches the owner and name of a repository in any URL on GitHub, e.g. an archive
+var projectRegex = regexp.MustCompile(`^https?://github\.com/([^/]+)/([^/]+?)(?:\.git)?(?:/|$)`)
+
+// commitRegex matches a full or abbreviated commit hash
+var commitRegex = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// Commit is a single commit of a repository
+type Commit struct {
+	SHA  string
+	Date time.Time
+}
+
+// IsCommit checks if a git ref is a commit hash, rather than a tag or branch.
+// Abbreviated hashes need a letter, so that tags made of digits only (e.g. dates) are not mistaken for one.
+func IsCommit(ref string) bool {
+	if !commitRegex.MatchString(ref) {
+		return false
+	}
+	return len(ref) == 40 || strings.ContainsAny(ref, "abcdefABCDEF")
+}
+
+// Match gets the "owner/name" of a GitHub repository from its clone URL, or "" if not on GitHub
+func Match(url string) string {
+	pieces := repoRegex.FindStringSubmatch(url)
+	if pieces == nil {
+		return ""
+	}
+	return pieces[1] + "/" + pieces[2]
+}
+
+// ReleaseURL gets the page of a tagged release for any URL of a GitHub project, or "" if not on GitHub
+func ReleaseURL(url, tag string) string {
+	pieces := projectRegex.FindStringSubmatch(url)
+	if pieces == nil {
+		return ""
+	}
+	return "https://github.com/" + pieces[1] + "/" + pieces[2] + "/releases/tag/" + tag
+}
+
+// get decodes the response of a single API request, authenticated by $GITHUB_TOKEN if set
+func get(path string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, APIBase+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status '%s' for '%s'", resp.Status, path)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// GetCommit finds a commit of a repository by hash or branch name
+func GetCommit(repo, ref string) (c Commit, err error) {
+	var raw struct {
+		SHA    string `json:"sha"`
+		Commit struct {
+			Committer struct {
+				Date time.Time `json:"date"`
+			} `json:"committer"`
+		} `json:"commit"`
+	}
+	if err = get("/repos/"+repo+"/commits/"+ref, &raw); err != nil {
+		return
+	}
+	c.SHA = raw.SHA
+	c.Date = raw.Commit.Committer.Date
+	return
+}
+
+// Head finds the newest commit on the default branch of a repository
+func Head(repo string) (c Commit, err error) {
+	var raw struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err = get("/repos/"+repo, &raw); err != nil {
+		return
+	}
+	return GetCommit(repo, raw.DefaultBranch)
+}
+
+// CountBetween gets how many commits are reachable from head but not from base
+func CountBetween(repo, base, head string) (int, error) {
+	var raw struct {
+		AheadBy int `json:"ahead_by"`
+	}
+	err := get("/repos/"+repo+"/compare/"+base+"..."+head, &raw)
+	return raw.AheadBy, err
+}
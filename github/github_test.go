@@ -0,0 +1,40 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package github
+
+import (
+	"testing"
+)
+
+func TestIsCommit(t *testing.T) {
+	tests := map[string]bool{
+		"4f2a9c1":  true,
+		"4F2A9C1E": true,
+		"0123456789012345678901234567890123456789": true,
+		"4f2a9c1e0b7d3a5f6e8c9b0a1d2e3f4a5b6c7d8e": true,
+		"20181004": false,
+		"1234567":  false,
+		"v1.2.3":   false,
+		"abc12":    false,
+		"master":   false,
+	}
+	for ref, expected := range tests {
+		if IsCommit(ref) != expected {
+			t.Errorf("Expected IsCommit(%q) to be %t", ref, expected)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/DataDrake/ypkg-update-checker/config"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"github.com/jmoiron/sqlx"
+	"net/http"
+	"os"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	// EventOutOfDate is sent the first time each new upstream version of a source is found
+	EventOutOfDate = "out-of-date"
+	// EventFailing is sent when the provider of a source starts failing
+	EventFailing = "provider-failing"
+)
+
+// DefaultRetries is how many more times a webhook is tried after a failure, unless configured
+const DefaultRetries = 3
+
+// QueueSize is how many Events can wait to be delivered before Notify blocks
+const QueueSize = 256
+
+// Event is a single status transition of a source
+type Event struct {
+	Event    string    `json:"event"`
+	Package  string    `json:"package"`
+	Index    int       `json:"index"`
+	Current  string    `json:"current"`
+	Latest   string    `json:"latest"`
+	Previous string    `json:"previous"`
+	Source   string    `json:"source"`
+	Time     time.Time `json:"time"`
+}
+
+// webhook is a configured Webhook with its template already parsed
+type webhook struct {
+	config.Webhook
+	retries int
+	tmpl    *template.Template
+}
+
+// Notifier sends Events to every configured webhook, in the background so that a slow or failing
+// webhook does not hold up the checks
+type Notifier struct {
+	rdb    *sqlx.DB
+	hooks  []webhook
+	client *http.Client
+	queue  chan Event
+	// pending counts the Events queued but not yet delivered
+	pending sync.WaitGroup
+}
+
+// templateFuncs are available to webhook templates, "json" quotes a value for use inside JSON
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		raw, err := json.Marshal(v)
+		return string(raw), err
+	},
+}
+
+// New creates a Notifier for the configured webhooks
+func New(rdb *sqlx.DB, hooks []config.Webhook) (*Notifier, error) {
+	n := &Notifier{
+		rdb:    rdb,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, hook := range hooks {
+		h := webhook{Webhook: hook, retries: DefaultRetries}
+		if h.Retries != nil {
+			h.retries = *h.Retries
+		}
+		if h.ContentType == "" {
+			h.ContentType = "application/json"
+		}
+		if h.Template != "" {
+			tmpl, err := template.New(h.URL).Funcs(templateFuncs).Parse(h.Template)
+			if err != nil {
+				return nil, fmt.Errorf("bad template for webhook '%s': %s", h.URL, err.Error())
+			}
+			h.tmpl = tmpl
+		}
+		n.hooks = append(n.hooks, h)
+	}
+	if len(n.hooks) > 0 {
+		n.queue = make(chan Event, QueueSize)
+		go n.deliver()
+	}
+	return n, nil
+}
+
+// Flush waits until every queued Event has been delivered, or has failed
+func (n *Notifier) Flush() {
+	if n == nil {
+		return
+	}
+	n.pending.Wait()
+}
+
+// Close delivers the queued Events, then stops sending any more
+func (n *Notifier) Close() {
+	if n == nil || n.queue == nil {
+		return
+	}
+	n.Flush()
+	close(n.queue)
+}
+
+// Notify compares the releases of a package before and after a check, matched by Index, and queues an Event
+// for every transition of a primary source. Failed deliveries are only logged, since they happen later.
+func (n *Notifier) Notify(prev, curr []db.Release) error {
+	if n == nil || len(n.hooks) == 0 {
+		return nil
+	}
+	saved := make(map[int]db.Release)
+	for _, r := range prev {
+		saved[r.Index] = r
+	}
+	for _, r := range curr {
+		if r.Auxiliary {
+			continue
+		}
+		old, ok := saved[r.Index]
+		if !ok {
+			old = db.Release{Latest: "N/A", Status: db.StatusUnmatched}
+		}
+		switch {
+		case r.Status == db.StatusOutOfDate:
+			if err := n.outOfDate(old, r); err != nil {
+				return err
+			}
+		case r.Status == db.StatusFailed && old.Status != db.StatusFailed:
+			n.send(newEvent(EventFailing, old, r))
+		}
+	}
+	return nil
+}
+
+// outOfDate announces a new upstream version when a package becomes out of date or its latest version
+// changes, unless it has been announced before
+func (n *Notifier) outOfDate(old, r db.Release) error {
+	announced, err := db.Announced(n.rdb, r)
+	if err != nil || announced {
+		return err
+	}
+	if old.Status == db.StatusOutOfDate && old.Latest == r.Latest {
+		// Not a transition, e.g. already out of date before the webhook was added, so only recorded
+		return db.MarkAnnounced(n.rdb, r)
+	}
+	n.send(newEvent(EventOutOfDate, old, r))
+	// Recorded even if a webhook fails, so that the others are not sent the same Event again
+	return db.MarkAnnounced(n.rdb, r)
+}
+
+func newEvent(kind string, old, r db.Release) Event {
+	return Event{
+		Event:    kind,
+		Package:  r.Package,
+		Index:    r.Index,
+		Current:  r.Current,
+		Latest:   r.Latest,
+		Previous: old.Latest,
+		Source:   r.Source,
+		Time:     time.Now(),
+	}
+}
+
+// send queues an Event for every webhook
+func (n *Notifier) send(e Event) {
+	n.pending.Add(1)
+	n.queue <- e
+}
+
+// deliver sends the queued Events to every webhook in turn, until the Notifier is closed
+func (n *Notifier) deliver() {
+	for e := range n.queue {
+		for _, hook := range n.hooks {
+			if err := n.post(hook, e); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to send '%s' of %s to webhook '%s', reason: %s\n",
+					e.Event, e.Package, hook.URL, err.Error())
+			}
+		}
+		n.pending.Done()
+	}
+}
+
+// post delivers an Event to a single webhook, retrying with an increasing delay
+func (n *Notifier) post(hook webhook, e Event) error {
+	var body bytes.Buffer
+	var err error
+	if hook.tmpl != nil {
+		err = hook.tmpl.Execute(&body, e)
+	} else {
+		err = json.NewEncoder(&body).Encode(e)
+	}
+	if err != nil {
+		return err
+	}
+	delay := time.Second
+	for attempt := 0; ; attempt++ {
+		var resp *http.Response
+		resp, err = n.client.Post(hook.URL, hook.ContentType, bytes.NewReader(body.Bytes()))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 300 {
+				return nil
+			}
+			err = fmt.Errorf("unexpected status '%s'", resp.Status)
+		}
+		if attempt >= hook.retries {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
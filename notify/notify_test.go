@@ -0,0 +1,148 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package notify
+
+import (
+	"github.com/DataDrake/ypkg-update-checker/config"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	_ "github.com/mattn/go-sqlite3"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestNotifier creates a Notifier for a single webhook answering with a status, counting its requests
+func newTestNotifier(t *testing.T, status int, retries *int) (*Notifier, *int32, func()) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(status)
+	}))
+	rdb, err := db.Connect(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %s", err)
+	}
+	n, err := New(rdb, []config.Webhook{{URL: server.URL, Retries: retries}})
+	if err != nil {
+		t.Fatalf("Failed to set up notifications: %s", err)
+	}
+	return n, &count, func() {
+		n.Close()
+		server.Close()
+		rdb.Close()
+	}
+}
+
+func TestNotifyTransitions(t *testing.T) {
+	n, count, teardown := newTestNotifier(t, http.StatusOK, nil)
+	defer teardown()
+	tests := []struct {
+		old, curr db.Release
+		sent      int32
+	}{
+		// Already out of date when the webhook was added
+		{
+			db.Release{Package: "foo", Latest: "1.1", Status: db.StatusOutOfDate},
+			db.Release{Package: "foo", Latest: "1.1", Status: db.StatusOutOfDate},
+			0,
+		},
+		// Flapping back to the same version is not announced, since it was recorded
+		{
+			db.Release{Package: "foo", Latest: "1.1", Status: db.StatusFailed},
+			db.Release{Package: "foo", Latest: "1.1", Status: db.StatusOutOfDate},
+			0,
+		},
+		{
+			db.Release{Package: "foo", Latest: "1.1", Status: db.StatusOutOfDate},
+			db.Release{Package: "foo", Latest: "1.2", Status: db.StatusOutOfDate},
+			1,
+		},
+		{
+			db.Release{Package: "bar", Latest: "2.0", Status: db.StatusUpToDate},
+			db.Release{Package: "bar", Latest: "2.1", Status: db.StatusOutOfDate},
+			2,
+		},
+		// Extra sources never announce anything
+		{
+			db.Release{Package: "bar", Index: 1, Latest: "1.0", Status: db.StatusUpToDate, Auxiliary: true},
+			db.Release{Package: "bar", Index: 1, Latest: "1.1", Status: db.StatusOutOfDate, Auxiliary: true},
+			2,
+		},
+	}
+	for i, test := range tests {
+		if err := n.Notify([]db.Release{test.old}, []db.Release{test.curr}); err != nil {
+			t.Fatalf("Failed to notify: %s", err)
+		}
+		n.Flush()
+		if sent := atomic.LoadInt32(count); sent != test.sent {
+			t.Errorf("Step %d: expected %d events sent, found %d", i, test.sent, sent)
+		}
+	}
+}
+
+func TestNotifyNoRetries(t *testing.T) {
+	none := 0
+	n, count, teardown := newTestNotifier(t, http.StatusInternalServerError, &none)
+	defer teardown()
+	old := db.Release{Package: "foo", Latest: "1.0", Status: db.StatusUpToDate}
+	curr := db.Release{Package: "foo", Latest: "1.1", Status: db.StatusOutOfDate}
+	if err := n.Notify([]db.Release{old}, []db.Release{curr}); err != nil {
+		t.Errorf("Expected a failing webhook to be left to the queue, found: %s", err)
+	}
+	n.Flush()
+	if sent := atomic.LoadInt32(count); sent != 1 {
+		t.Errorf("Expected a single attempt, found %d", sent)
+	}
+}
+
+func TestNotifyQueued(t *testing.T) {
+	release := make(chan bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	rdb, err := db.Connect(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %s", err)
+	}
+	defer rdb.Close()
+	n, err := New(rdb, []config.Webhook{{URL: server.URL}})
+	if err != nil {
+		t.Fatalf("Failed to set up notifications: %s", err)
+	}
+	old := db.Release{Package: "foo", Latest: "1.0", Status: db.StatusUpToDate}
+	curr := db.Release{Package: "foo", Latest: "1.1", Status: db.StatusOutOfDate}
+	done := make(chan error)
+	go func() {
+		done <- n.Notify([]db.Release{old}, []db.Release{curr})
+	}()
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Errorf("Failed to notify: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected Notify to return before the webhook answers")
+	}
+	if announced, _ := db.Announced(rdb, curr); !announced {
+		t.Error("Expected the Event to be recorded as soon as it is queued")
+	}
+	close(release)
+	n.Close()
+}
@@ -0,0 +1,143 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/DataDrake/ypkg-update-checker/github"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// versionLineRegex matches the value of "version" in a package.yml, keeping any quotes and comments around it
+var versionLineRegex = regexp.MustCompile(`(?m)^(version\s*:\s*["']?)([^"'\s#]+)`)
+
+// releaseLineRegex matches the value of "release" in a package.yml
+var releaseLineRegex = regexp.MustCompile(`(?m)^(release\s*:\s*)([0-9]+)`)
+
+var downloadClient = &http.Client{Timeout: 10 * time.Minute}
+
+// Next derives the Source of a newer version, using the upstream location if it is an archive,
+// or else replacing the version in the old URL or tag
+func (s Source) Next(current, latest, location string) (Source, error) {
+	next := s
+	old := s.Version()
+	if old == "" {
+		old = current
+	}
+	switch s.Kind {
+	case SourceGit:
+		if github.IsCommit(s.Ref) {
+			return next, errors.New("sources pinned to a commit cannot be bumped")
+		}
+		if !strings.Contains(s.Ref, old) {
+			return next, fmt.Errorf("cannot find version '%s' in ref '%s'", old, s.Ref)
+		}
+		next.Ref = replaceLast(s.Ref, old, strings.TrimLeft(latest, "vV"))
+	default:
+		if archiveRegex.MatchString(path.Base(location)) {
+			next.URL = location
+		} else {
+			if !strings.Contains(s.URL, old) {
+				return next, fmt.Errorf("cannot find version '%s' in URL '%s'", old, s.URL)
+			}
+			next.URL = replaceVersion(s.URL, old, strings.TrimLeft(latest, "vV"))
+		}
+		next.SHA256 = ""
+	}
+	return next, nil
+}
+
+// replaceLast replaces only the last occurrence of old in s
+func replaceLast(s, old, next string) string {
+	i := strings.LastIndex(s, old)
+	if i < 0 {
+		return s
+	}
+	return s[:i] + next + s[i+len(old):]
+}
+
+// replaceVersion replaces a version in the file name of a URL, or else its last occurrence, so that
+// a short version (e.g. "2.0") does not rewrite the host or any other part of the path
+func replaceVersion(url, old, next string) string {
+	name := path.Base(url)
+	if strings.Contains(name, old) {
+		return url[:len(url)-len(name)] + replaceLast(name, old, next)
+	}
+	return replaceLast(url, old, next)
+}
+
+// Download fetches a tarball and gets its sha256sum
+func Download(url string) (string, error) {
+	resp, err := downloadClient.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status '%s' for '%s'", resp.Status, url)
+	}
+	sum := sha256.New()
+	if _, err = io.Copy(sum, resp.Body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// Rewrite updates the version, release and a single source of a package.yml, editing its text
+// so that comments and formatting are preserved
+func Rewrite(raw []byte, version string, old, next Source) ([]byte, error) {
+	text := string(raw)
+	if !versionLineRegex.MatchString(text) {
+		return nil, errors.New("no version found")
+	}
+	text = versionLineRegex.ReplaceAllString(text, "${1}"+version)
+	if !releaseLineRegex.MatchString(text) {
+		return nil, errors.New("no release found")
+	}
+	text = releaseLineRegex.ReplaceAllStringFunc(text, func(line string) string {
+		found := releaseLineRegex.FindStringSubmatch(line)
+		release, _ := strconv.Atoi(found[2])
+		return found[1] + strconv.Itoa(release+1)
+	})
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		start := strings.Index(line, old.Location())
+		if start < 0 {
+			continue
+		}
+		// The value comes after the location, e.g. "- https://.../foo-1.2.3.tar.gz : <sha256sum>"
+		rest := line[start+len(old.Location()):]
+		if !strings.Contains(rest, old.Value()) {
+			continue
+		}
+		if old.Value() != "" {
+			rest = strings.Replace(rest, old.Value(), next.Value(), 1)
+		}
+		lines[i] = line[:start] + next.Location() + rest
+		return []byte(strings.Join(lines, "\n")), nil
+	}
+	return nil, fmt.Errorf("no source found for '%s'", old.Location())
+}
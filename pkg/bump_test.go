@@ -0,0 +1,59 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package pkg
+
+import (
+	"testing"
+)
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		source, value, current, latest, location string
+		url, ref                                 string
+	}{
+		{
+			"https://example.com/foo-1.0.tar.gz", "0", "1.0", "1.1", "https://example.com/foo/1.1/",
+			"https://example.com/foo-1.1.tar.gz", "",
+		},
+		{
+			"https://example2.com/2.0/foo-2.0.tar.gz", "0", "2.0", "2.1", "https://example2.com/",
+			"https://example2.com/2.0/foo-2.1.tar.gz", "",
+		},
+		{
+			"https://files1.example.com/foo/1/download", "0", "1", "2", "https://example.com/",
+			"https://files1.example.com/foo/2/download", "",
+		},
+		{
+			"https://example.com/foo-1.0.tar.gz", "0", "1.0", "1.2", "https://mirror.example.com/foo-1.2.tar.xz",
+			"https://mirror.example.com/foo-1.2.tar.xz", "",
+		},
+		{
+			"git|https://example.com/foo2.git", "v2", "2", "v3", "https://example.com/",
+			"https://example.com/foo2.git", "v3",
+		},
+	}
+	for _, test := range tests {
+		next, err := ParseSource(test.source, test.value).Next(test.current, test.latest, test.location)
+		if err != nil {
+			t.Errorf("Failed to bump '%s': %s", test.source, err)
+			continue
+		}
+		if next.URL != test.url || next.Ref != test.ref {
+			t.Errorf("Expected '%s' at '%s', found '%s' at '%s'", test.url, test.ref, next.URL, next.Ref)
+		}
+	}
+}
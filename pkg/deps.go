@@ -0,0 +1,143 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package pkg
+
+import (
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"sort"
+	"strings"
+)
+
+// subpackageSuffixes are removed from a dependency to find the package that builds it, e.g. "foo-devel"
+var subpackageSuffixes = []string{"-32bit-devel", "-32bit", "-devel", "-docs", "-utils", "-dbginfo"}
+
+// Graph links every package to the packages it depends on, for building or at runtime, and back
+type Graph struct {
+	deps  map[string][]string
+	rdeps map[string][]string
+}
+
+// Impact is the packages affected by an update to a single package, either because they depend on it
+// themselves (Direct) or through any of the others (Transitive, which includes the Direct ones)
+type Impact struct {
+	Direct     []string `json:"direct"`
+	Transitive []string `json:"transitive"`
+}
+
+// NewGraph builds a Graph from the metadata of every package, ignoring dependencies that are not
+// one of the packages or their subpackages (e.g. "pkgconfig(...)")
+func NewGraph(packages map[string]db.Package) *Graph {
+	g := &Graph{
+		deps:  make(map[string][]string),
+		rdeps: make(map[string][]string),
+	}
+	for name, p := range packages {
+		seen := make(map[string]bool)
+		for _, dep := range append(append([]string{}, p.BuildDeps...), p.RunDeps...) {
+			dep = sourcePackage(dep, packages)
+			if dep == "" || dep == name || seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			g.deps[name] = append(g.deps[name], dep)
+			g.rdeps[dep] = append(g.rdeps[dep], name)
+		}
+		sort.Strings(g.deps[name])
+	}
+	for _, names := range g.rdeps {
+		sort.Strings(names)
+	}
+	return g
+}
+
+// sourcePackage finds the package that builds a dependency, returning "" if none of them do
+func sourcePackage(dep string, packages map[string]db.Package) string {
+	if _, ok := packages[dep]; ok {
+		return dep
+	}
+	for _, suffix := range subpackageSuffixes {
+		if name := strings.TrimSuffix(dep, suffix); name != dep {
+			if _, ok := packages[name]; ok {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// Dependencies gets the packages that a package depends on directly
+func (g *Graph) Dependencies(name string) []string {
+	return g.deps[name]
+}
+
+// Dependents gets the packages that depend on a package directly
+func (g *Graph) Dependents(name string) []string {
+	return g.rdeps[name]
+}
+
+// Impact gets the packages that depend on a package, directly or through any others
+func (g *Graph) Impact(name string) Impact {
+	impact := Impact{
+		Direct:     append([]string{}, g.rdeps[name]...),
+		Transitive: make([]string, 0),
+	}
+	seen := map[string]bool{name: true}
+	queue := append([]string{}, g.rdeps[name]...)
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if seen[next] {
+			continue
+		}
+		seen[next] = true
+		impact.Transitive = append(impact.Transitive, next)
+		queue = append(queue, g.rdeps[next]...)
+	}
+	sort.Strings(impact.Transitive)
+	return impact
+}
+
+// Order sorts some of the packages so that each comes after any of the others that it depends on,
+// breaking any cycles by name
+func (g *Graph) Order(names []string) []string {
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		wanted[name] = true
+	}
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+	order := make([]string, 0)
+	visited := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		// Followed through the other packages too, which may link two of the wanted ones
+		for _, dep := range g.deps[name] {
+			visit(dep)
+		}
+		if wanted[name] {
+			order = append(order, name)
+		}
+	}
+	for _, name := range sorted {
+		visit(name)
+	}
+	return order
+}
@@ -0,0 +1,143 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package pkg
+
+import (
+	"fmt"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"io"
+	"time"
+)
+
+// DiffHeader is the format string for the start of a diff report
+const DiffHeader = `
+<h1 id="summary">Changes since %s</h1>
+<table>
+<tr><td><a href="#outofdate">Newly Out of Date</a></td><td>%d</td></tr>
+<tr><td><a href="#updated">Updated by Packagers</a></td><td>%d</td></tr>
+<tr><td><a href="#unmatched">Newly Unmatched</a></td><td>%d</td></tr>
+<tr><td><a href="#failing">Provider Failing</a></td><td>%d</td></tr>
+</table>
+`
+
+// DiffSectionStart is the format string for the header of a single diff section
+const DiffSectionStart = `
+<h1 id="%s">%s</h1>
+<table>
+<thead>
+<tr><th>Name</th><th>Was</th><th>Now</th><th>Location</th></tr>
+</thead>
+<tbody>
+`
+
+// DiffRow is the format string for a single change in a diff section
+const DiffRow = "<tr><td>%s</td><td>%s</td><td class=\"%s\">%s</td><td><a href=\"%s\">%s</a></td></tr>\n"
+
+// DiffClose terminates a diff report
+const DiffClose = `
+<h3><a href="#summary">Back to Top</a></h3>
+</body>
+`
+
+// Change is the previous and current state of a single source
+type Change struct {
+	Previous db.Snapshot `json:"previous"`
+	Release  db.Release  `json:"release"`
+}
+
+// Diff is a record of the sources which changed since an earlier point in time
+type Diff struct {
+	Since     time.Time `json:"since"`
+	OutOfDate []Change  `json:"out_of_date"`
+	Updated   []Change  `json:"updated"`
+	Unmatched []Change  `json:"unmatched"`
+	Failing   []Change  `json:"failing"`
+}
+
+// NewDiff compares the current releases against their state at an earlier point in time.
+// Auxiliary sources are left out, since they do not change the status of their package.
+func NewDiff(since time.Time, prev map[string]map[int]db.Snapshot, releases []db.Release) *Diff {
+	d := &Diff{
+		Since:     since,
+		OutOfDate: make([]Change, 0),
+		Updated:   make([]Change, 0),
+		Unmatched: make([]Change, 0),
+		Failing:   make([]Change, 0),
+	}
+	for _, release := range releases {
+		if release.Auxiliary {
+			continue
+		}
+		old, ok := prev[release.Package][release.Index]
+		if !ok {
+			old = db.Snapshot{
+				Package: release.Package,
+				Index:   release.Index,
+				Current: "N/A",
+				Latest:  "N/A",
+				Status:  db.StatusMissingYML,
+			}
+		}
+		change := Change{old, release}
+		if ok && old.Current != release.Current {
+			d.Updated = append(d.Updated, change)
+		}
+		if old.Status == release.Status {
+			continue
+		}
+		switch release.Status {
+		case db.StatusOutOfDate:
+			d.OutOfDate = append(d.OutOfDate, change)
+		case db.StatusUnmatched:
+			d.Unmatched = append(d.Unmatched, change)
+		case db.StatusFailed:
+			d.Failing = append(d.Failing, change)
+		}
+	}
+	return d
+}
+
+// Print generates an HTML report of the changes
+func (d Diff) Print(w io.Writer) {
+	fmt.Fprint(w, ReportStart)
+	fmt.Fprintf(w, DiffHeader, d.Since.Format(HistoryTimeFormat),
+		len(d.OutOfDate), len(d.Updated), len(d.Unmatched), len(d.Failing))
+	printDiffSection(w, "outofdate", "Newly Out of Date", d.OutOfDate, true)
+	printDiffSection(w, "updated", "Updated by Packagers", d.Updated, false)
+	printDiffSection(w, "unmatched", "Newly Unmatched", d.Unmatched, true)
+	printDiffSection(w, "failing", "Provider Failing", d.Failing, true)
+	fmt.Fprint(w, DiffClose)
+}
+
+// PrintJSON generates a JSON report of the changes
+func (d Diff) PrintJSON(w io.Writer) error {
+	return printJSON(w, d)
+}
+
+// printDiffSection shows either the upstream or packaged versions of each change
+func printDiffSection(w io.Writer, id, title string, changes []Change, upstream bool) {
+	fmt.Fprintf(w, DiffSectionStart, id, title)
+	for _, change := range changes {
+		was, now := change.Previous.Current, change.Release.Current
+		if upstream {
+			was, now = change.Previous.Latest, change.Release.Latest
+		}
+		fmt.Fprintf(w, DiffRow, change.Release.Package, was, statusClass(change.Release.Status), now,
+			change.Release.Source, change.Release.Source)
+	}
+	fmt.Fprint(w, ReportTableClose)
+}
@@ -0,0 +1,150 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package pkg
+
+import (
+	"fmt"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"time"
+)
+
+// HistoryTimeFormat is the layout used for every timestamp in a History
+const HistoryTimeFormat = "2006-01-02 15:04"
+
+// Event is a single change to a source
+type Event struct {
+	Time        time.Time
+	Description string
+}
+
+// Lag is the delay between a version being released upstream and it being packaged
+type Lag struct {
+	Version  string
+	Upstream time.Time
+	Packaged time.Time
+}
+
+// String describes the Lag or why it could not be worked out
+func (l Lag) String() string {
+	switch {
+	case l.Upstream.IsZero():
+		return "N/A"
+	case l.Packaged.IsZero():
+		return "pending for " + FormatDuration(time.Since(l.Upstream))
+	case l.Packaged.Before(l.Upstream):
+		return "packaged before upstream was seen"
+	default:
+		return FormatDuration(l.Packaged.Sub(l.Upstream))
+	}
+}
+
+// SourceHistory is the timeline of a single source of a package
+type SourceHistory struct {
+	Index  int
+	Events []Event
+	Lags   []*Lag
+}
+
+// History is the timeline of every source of a package
+type History struct {
+	Package string
+	Sources []*SourceHistory
+}
+
+// NewHistory builds a timeline from the Snapshots of a package, ordered by index and time
+func NewHistory(name string, snapshots []db.Snapshot) *History {
+	h := &History{Package: name}
+	var src *SourceHistory
+	var prev db.Snapshot
+	var lags map[string]*Lag
+	for _, snap := range snapshots {
+		first := src == nil || src.Index != snap.Index
+		if first {
+			src = &SourceHistory{Index: snap.Index}
+			h.Sources = append(h.Sources, src)
+			prev = db.Snapshot{Latest: "N/A"}
+			lags = make(map[string]*Lag)
+		}
+		if snap.Latest != prev.Latest && snap.Latest != "N/A" && snap.Latest != "" {
+			src.Events = append(src.Events, Event{snap.Seen, "Upstream released " + snap.Latest})
+			lag := lags[snap.Latest]
+			if lag == nil {
+				lag = &Lag{Version: snap.Latest}
+				lags[snap.Latest] = lag
+				src.Lags = append(src.Lags, lag)
+			}
+			if lag.Upstream.IsZero() {
+				lag.Upstream = snap.Seen
+			}
+		}
+		if snap.Current != prev.Current {
+			src.Events = append(src.Events, Event{snap.Seen, "Packaged " + snap.Current})
+			lag := lags[snap.Current]
+			if lag == nil {
+				lag = &Lag{Version: snap.Current}
+				lags[snap.Current] = lag
+				src.Lags = append(src.Lags, lag)
+			}
+			if lag.Packaged.IsZero() {
+				lag.Packaged = snap.Seen
+			}
+		}
+		if first || snap.Status != prev.Status {
+			src.Events = append(src.Events, Event{snap.Seen, "Status changed to " + db.StatusNames[snap.Status]})
+		}
+		prev = snap
+	}
+	return h
+}
+
+// Print writes out the timeline of each source, followed by the lag for each version
+func (h History) Print() {
+	if len(h.Sources) == 0 {
+		fmt.Printf("No history recorded for '%s'.\n", h.Package)
+		return
+	}
+	fmt.Printf("History for '%s'\n", h.Package)
+	for _, src := range h.Sources {
+		fmt.Printf("\nSource %d:\n\n", src.Index)
+		for _, event := range src.Events {
+			fmt.Printf("    %s  %s\n", event.Time.Format(HistoryTimeFormat), event.Description)
+		}
+		if len(src.Lags) == 0 {
+			continue
+		}
+		fmt.Printf("\n    %-20s %-18s %-18s %s\n", "Version", "Upstream", "Packaged", "Lag")
+		for _, lag := range src.Lags {
+			fmt.Printf("    %-20s %-18s %-18s %s\n", lag.Version, formatTime(lag.Upstream), formatTime(lag.Packaged), lag)
+		}
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "N/A"
+	}
+	return t.Format(HistoryTimeFormat)
+}
+
+// FormatDuration rounds a Duration to the nearest hour and prints it in days and hours
+func FormatDuration(d time.Duration) string {
+	hours := int(d.Round(time.Hour) / time.Hour)
+	if hours < 24 {
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dd %dh", hours/24, hours%24)
+}
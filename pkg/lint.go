@@ -0,0 +1,51 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package pkg
+
+import (
+	"fmt"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"path"
+	"regexp"
+)
+
+// ownSourceRegex matches the file name of a tarball of the package itself, e.g. "foo-1.2.3.tar.gz" for "foo"
+func (yml PackageYML) ownSourceRegex() *regexp.Regexp {
+	return regexp.MustCompile("^" + regexp.QuoteMeta(yml.Name) + "[-_][vV]?[0-9]")
+}
+
+// Lint finds mistakes in a package.yml, such as a version that disagrees with the version in a source URL.
+// Auxiliary sources are only checked if they look like a tarball of the package itself.
+func (yml PackageYML) Lint() []string {
+	findings := make([]string, 0)
+	own := yml.ownSourceRegex()
+	for i, src := range yml.Sources {
+		if i > 0 && !own.MatchString(path.Base(src.URL)) {
+			continue
+		}
+		found := src.Version()
+		if found == "" || yml.Version == "" {
+			continue
+		}
+		// Every piece must match, so "1.2" is not taken for "1.2.1"
+		if db.NewVersion(found).CompareStrict(db.NewVersion(yml.Version)) != 0 {
+			findings = append(findings, fmt.Sprintf("version '%s' does not match '%s' in source '%s'",
+				yml.Version, found, src.Location()))
+		}
+	}
+	return findings
+}
@@ -0,0 +1,45 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package pkg
+
+import (
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		version  string
+		sources  []string
+		findings int
+	}{
+		{"1.2.1", []string{"https://example.com/foo-1.2.1.tar.gz"}, 0},
+		{"1.2", []string{"https://example.com/foo-1.2.1.tar.gz"}, 1},
+		{"1.2.1", []string{"https://example.com/foo-1.2.tar.gz"}, 1},
+		{"1.2.1", []string{"https://example.com/foo-v1.2.1.tar.gz"}, 0},
+		{"1.2.1", []string{"https://example.com/foo-1.2.1.tar.gz", "https://example.com/foo-1.2.tar.gz"}, 1},
+		{"1.2.1", []string{"https://example.com/foo-1.2.1.tar.gz", "https://example.com/data-1.0.tar.gz"}, 0},
+	}
+	for _, test := range tests {
+		yml := PackageYML{Name: "foo", Version: test.version}
+		for _, src := range test.sources {
+			yml.Sources = append(yml.Sources, ParseSource(src, ""))
+		}
+		if findings := yml.Lint(); len(findings) != test.findings {
+			t.Errorf("Expected %d findings for %s in %v, found %v", test.findings, test.version, test.sources, findings)
+		}
+	}
+}
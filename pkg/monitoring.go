@@ -0,0 +1,105 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package pkg
+
+import (
+	"fmt"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"gopkg.in/yaml.v2"
+	"os"
+	"regexp"
+	"time"
+)
+
+// Upstream identifies a project on a single provider
+type Upstream struct {
+	Provider string `yaml:"provider"`
+	ID       string `yaml:"id"`
+}
+
+// MonitoringYML is a Go representation of a monitoring.yml file, e.g.:
+//
+//	upstream:
+//	    provider: GitHub
+//	    id: foo/bar
+//	version_regex: '^v?([0-9.]+)$'
+//	ignore:
+//	    - 'rc'
+//	series:
+//	    - '3.11'
+//	    - '3.12'
+//	interval: 24h
+type MonitoringYML struct {
+	Upstream     Upstream   `yaml:"upstream"`
+	VersionRegex string     `yaml:"version_regex"`
+	Ignore       []string   `yaml:"ignore"`
+	Series       StringList `yaml:"series"`
+	Interval     string     `yaml:"interval"`
+}
+
+// OpenMonitoring parses a monitoring.yml into a struct and returns it
+func OpenMonitoring(path string) (m *MonitoringYML, err error) {
+	mFile, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer mFile.Close()
+	dec := yaml.NewDecoder(mFile)
+	m = &MonitoringYML{}
+	err = dec.Decode(m)
+	return
+}
+
+// Compile checks the overrides against the providers of a Registry and prepares them for use by Release.Check,
+// one for each tracked series
+func (m MonitoringYML) Compile(reg db.Registry) ([]*db.Monitoring, error) {
+	if m.Upstream.Provider != "" && db.FindProvider(reg, m.Upstream.Provider) == nil {
+		return nil, fmt.Errorf("unknown provider '%s'", m.Upstream.Provider)
+	}
+	compiled := &db.Monitoring{
+		Provider: m.Upstream.Provider,
+		ID:       m.Upstream.ID,
+	}
+	var err error
+	if m.VersionRegex != "" {
+		if compiled.VersionRegex, err = regexp.Compile(m.VersionRegex); err != nil {
+			return nil, fmt.Errorf("bad version_regex: %s", err.Error())
+		}
+	}
+	for _, ignore := range m.Ignore {
+		re, err := regexp.Compile(ignore)
+		if err != nil {
+			return nil, fmt.Errorf("bad ignore pattern '%s': %s", ignore, err.Error())
+		}
+		compiled.Ignore = append(compiled.Ignore, re)
+	}
+	if m.Interval != "" {
+		if compiled.Interval, err = time.ParseDuration(m.Interval); err != nil {
+			return nil, fmt.Errorf("bad interval: %s", err.Error())
+		}
+	}
+	if len(m.Series) == 0 {
+		return []*db.Monitoring{compiled}, nil
+	}
+	tracked := make([]*db.Monitoring, 0)
+	for _, series := range m.Series {
+		copied := *compiled
+		copied.Series = series
+		tracked = append(tracked, &copied)
+	}
+	return tracked, nil
+}
@@ -0,0 +1,44 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package pkg
+
+import (
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"github.com/DataDrake/ypkg-update-checker/fake"
+	"testing"
+)
+
+func TestCompileProvider(t *testing.T) {
+	reg := db.ProviderList{fake.New("GitHub", nil)}
+	tests := map[string]bool{
+		"":        true,
+		"GitHub":  true,
+		"github":  true,
+		"Gitub":   false,
+		"Unknown": false,
+	}
+	for provider, valid := range tests {
+		m := MonitoringYML{Upstream: Upstream{Provider: provider, ID: "foo/bar"}}
+		compiled, err := m.Compile(reg)
+		if valid && (err != nil || len(compiled) != 1) {
+			t.Errorf("Expected provider '%s' to be accepted, found error: %v", provider, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("Expected provider '%s' to be rejected", provider)
+		}
+	}
+}
@@ -17,15 +17,122 @@
 package pkg
 
 import (
+	"fmt"
+	"github.com/DataDrake/ypkg-update-checker/db"
 	"gopkg.in/yaml.v2"
 	"os"
+	"sort"
 )
 
+// Maintainer is the person responsible for a package
+type Maintainer struct {
+	Name  string `yaml:"name"`
+	Email string `yaml:"email"`
+}
+
+// StringList is a field that may be either a single string or a list of strings
+type StringList []string
+
+// UnmarshalYAML accepts either form of a StringList
+func (l *StringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var single string
+	if err := unmarshal(&single); err == nil {
+		*l = StringList{single}
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// MainPackage is the key of the values for the main package in a PerPackage
+const MainPackage = ""
+
+// PerPackage is a field that may be set separately for each subpackage, e.g.:
+//
+//	rundeps:
+//	    - libfoo
+//	    - devel:
+//	        - libfoo-devel
+//
+// Values for the main package are kept under MainPackage
+type PerPackage map[string][]string
+
+// UnmarshalYAML accepts a single string, or a list of strings and subpackage mappings
+func (p *PerPackage) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*p = make(PerPackage)
+	var single string
+	if err := unmarshal(&single); err == nil {
+		(*p)[MainPackage] = []string{single}
+		return nil
+	}
+	var items []interface{}
+	if err := unmarshal(&items); err != nil {
+		return err
+	}
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			(*p)[MainPackage] = append((*p)[MainPackage], v)
+		case map[interface{}]interface{}:
+			for key, values := range v {
+				name := fmt.Sprint(key)
+				switch vs := values.(type) {
+				case []interface{}:
+					for _, value := range vs {
+						(*p)[name] = append((*p)[name], fmt.Sprint(value))
+					}
+				default:
+					(*p)[name] = append((*p)[name], fmt.Sprint(vs))
+				}
+			}
+		default:
+			return fmt.Errorf("unexpected value '%v'", item)
+		}
+	}
+	return nil
+}
+
+// Main gets the first value for the main package, if any
+func (p PerPackage) Main() string {
+	if values := p[MainPackage]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// All gets the values for every subpackage, starting with the main package
+func (p PerPackage) All() []string {
+	names := make([]string, 0)
+	for name := range p {
+		if name != MainPackage {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	all := append([]string{}, p[MainPackage]...)
+	for _, name := range names {
+		all = append(all, p[name]...)
+	}
+	return all
+}
+
 // PackageYML is a Go representation of a package.yml file
 type PackageYML struct {
-	Name    string              `yaml:"name"`
-	Version string              `yaml:"version"`
-	Sources []map[string]string `yaml:"source"`
+	Name       string      `yaml:"name"`
+	Version    string      `yaml:"version"`
+	Release    int         `yaml:"release"`
+	Sources    []Source    `yaml:"source"`
+	License    StringList  `yaml:"license"`
+	Homepage   string      `yaml:"homepage"`
+	Summary    PerPackage  `yaml:"summary"`
+	Component  PerPackage  `yaml:"component"`
+	BuildDeps  StringList  `yaml:"builddeps"`
+	RunDeps    PerPackage  `yaml:"rundeps"`
+	Maintainer *Maintainer `yaml:"maintainer"`
 }
 
 // Open parses a package.yml into a struct and returns it
@@ -40,3 +147,27 @@ func Open(path string) (yml *PackageYML, err error) {
 	err = dec.Decode(yml)
 	return
 }
+
+// Metadata gets the details of a package that are kept in the database
+func (yml PackageYML) Metadata(name string) db.Package {
+	p := db.Package{
+		Name:      name,
+		Version:   yml.Version,
+		Release:   yml.Release,
+		License:   db.List(yml.License),
+		Homepage:  yml.Homepage,
+		Summary:   yml.Summary.Main(),
+		Component: yml.Component.Main(),
+		BuildDeps: db.List(yml.BuildDeps),
+		RunDeps:   db.List(yml.RunDeps.All()),
+		Lint:      db.List(yml.Lint()),
+	}
+	if len(yml.Sources) > 0 {
+		p.Source = yml.Sources[0].URL
+	}
+	if yml.Maintainer != nil {
+		p.Maintainer = yml.Maintainer.Name
+		p.Email = yml.Maintainer.Email
+	}
+	return p
+}
@@ -17,11 +17,16 @@
 package pkg
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/DataDrake/ypkg-update-checker/db"
+	"github.com/DataDrake/ypkg-update-checker/security"
+	"html"
+	"io"
 	"math"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -44,6 +49,8 @@ a { color: #eee; text-decoration: none;1}
 .held {background-color: #F93; color: black;}
 .ok {background-color: #0F0; color: black;}
 .ahead {background-color: #0EF; color: black;}
+.failed {background-color: #999; color: black;}
+.security {background-color: #F0F; color: black; padding: 0 0.3rem;}
 </style>
 </head>
 <body>
@@ -76,17 +83,74 @@ const ReportMatchHeader = `
 <h1 id="matched">Matched Packages</h1>
 <table>
 <thead>
-<tr><th>Name</th><th>Old Version</th><th>New Version</th><th>Location</th></tr>
+<tr><th>Name</th><th>Component</th><th>Old Version</th><th>New Version</th><th>Newer Series</th><th>Behind For</th><th>Releases Behind</th><th>Dependents</th><th>Score</th><th>Advisories</th><th>Other Distros</th><th>Location</th></tr>
 </thead>
 <tbody>
 `
 
 // ReportMatchRow is the format string for a row of the matched packages
-const ReportMatchRow = "<tr><td>%s</td><td>%s</td><td class=\"%s\">%s</td><td><a href=\"%s\">%s</a></td></tr>\n"
+const ReportMatchRow = "<tr><td>%s</td><td>%s</td><td>%s</td><td class=\"%s\">%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td class=\"%s\">%s</td><td><a href=\"%s\">%s</a></td></tr>\n"
+
+// ReportComponentRow is the format string for the sub-header of a component, when sorting by component
+const ReportComponentRow = "<tr><th colspan=\"12\">%s</th></tr>\n"
+
+// ReportDistro is the format string for the newest version shipped by another distribution
+const ReportDistro = "%s (%s)"
+
+// ReportSecurityMarker is added to the name of a package with known security advisories
+const ReportSecurityMarker = " <span class=\"security\">security</span>"
+
+// ReportAdvisoryLink is the format string for a link to a single security advisory
+const ReportAdvisoryLink = "<a href=\"%s\">%s</a>"
+
+// ReportHomepageLink is the format string for the name of a package linked to its homepage
+const ReportHomepageLink = "<a href=\"%s\">%s</a>"
+
+// ReportDependents is the format string for the number of direct and transitive dependents of a package
+const ReportDependents = "%d (%d)"
+
+// ReportOrderHeader is the header for the suggested order of updating the out of date packages
+const ReportOrderHeader = `
+<h1 id="order">Suggested Update Order</h1>
+<table>
+<thead>
+<tr><th>Step</th><th>Name</th><th>New Version</th><th>Direct Dependents</th><th>Transitive Dependents</th></tr>
+</thead>
+<tbody>
+`
+
+// ReportOrderRow is the format string for a single step of the suggested update order
+const ReportOrderRow = "<tr><td>%d</td><td>%s</td><td>%s</td><td>%d</td><td>%d</td></tr>\n"
 
 // ReportTableClose terminates a table in the report
 const ReportTableClose = "</tbody></table>\n"
 
+// ReportAuxiliaryHeader is the header for the sources after the first of each package
+const ReportAuxiliaryHeader = `
+<h1 id="auxiliary">Auxiliary Sources</h1>
+<table>
+<thead>
+<tr><th>Name</th><th>Old Version</th><th>New Version</th><th>Location</th></tr>
+</thead>
+<tbody>
+`
+
+// ReportAuxiliaryRow is the format string for a row of the auxiliary sources
+const ReportAuxiliaryRow = "<tr><td>%s</td><td>%s</td><td class=\"%s\">%s</td><td><a href=\"%s\">%s</a></td></tr>\n"
+
+// ReportLintHeader is the header for the mistakes found in package.yml files
+const ReportLintHeader = `
+<h1 id="lint">Lint Findings</h1>
+<table>
+<thead>
+<tr><th>Name</th><th>Finding</th></tr>
+</thead>
+<tbody>
+`
+
+// ReportLintRow is the format string for a single lint finding
+const ReportLintRow = "<tr><td>%s</td><td>%s</td></tr>\n"
+
 // ReportUnmatchedHeader is the header for unmatched packages
 const ReportUnmatchedHeader = `
 <h1 id="unmatched">Unmatched Packages</h1>
@@ -96,7 +160,7 @@ const ReportUnmatchedSectionStart = `
 <h3>%s</h3>
 <table>
 <thead>
-<tr><th>Name</th><th>Old Version</th><th>Location</th></tr>
+<tr><th>Name</th><th>Old Version</th><th>Best Known Version</th><th>Location</th></tr>
 </thead>
 <tbody>
 `
@@ -104,7 +168,7 @@ const ReportUnmatchedSectionStart = `
 const ReportUnmatchedSectionStop = "</tbody></table>"
 
 // ReportUnmatchedRow is the format strign for a row of the unmatched packages
-const ReportUnmatchedRow = "<tr><td>%s</td><td>%s</td><td><a href=\"%s\">%s</a></td></tr>\n"
+const ReportUnmatchedRow = "<tr><td>%s</td><td>%s</td><td class=\"%s\">%s</td><td><a href=\"%s\">%s</a></td></tr>\n"
 
 // ReportUnmatchedClose terminates the report
 const ReportUnmatchedClose = `
@@ -117,25 +181,57 @@ type Report struct {
 	matched        []db.Release
 	unmatched      map[string][]db.Release
 	failed         []db.Release
+	auxiliary      []db.Release
 	unmatchedCount int
 	outOfDateCount int
 	heldBackCount  int
 	upToDateCount  int
 	aheadCount     int
+	stats          *Stats
+	packages       map[string]db.Package
+	impact         map[string]Impact
+	order          []string
+	distros        map[string]db.Distro
+	// byComponent groups the matched packages under a sub-header per component
+	byComponent bool
+}
+
+// jsonReport is the layout of a Report when printed as JSON
+type jsonReport struct {
+	Summary   map[string]int          `json:"summary"`
+	Matched   []db.Release            `json:"matched"`
+	Unmatched map[string][]db.Release `json:"unmatched"`
+	Failed    []db.Release            `json:"failed"`
+	Auxiliary []db.Release            `json:"auxiliary"`
+	Stats     *Stats                  `json:"stats,omitempty"`
+	Packages  map[string]db.Package   `json:"packages,omitempty"`
+	Impact    map[string]Impact       `json:"impact,omitempty"`
+	Order     []string                `json:"update_order,omitempty"`
+	Distros   map[string]db.Distro    `json:"distros,omitempty"`
 }
 
 func NewReport(releases []db.Release) *Report {
 	r := &Report{
-        unmatched: make(map[string][]db.Release),
-    }
+		matched:   make([]db.Release, 0),
+		unmatched: make(map[string][]db.Release),
+		failed:    make([]db.Release, 0),
+		auxiliary: make([]db.Release, 0),
+		packages:  make(map[string]db.Package),
+		impact:    make(map[string]Impact),
+		order:     make([]string, 0),
+		distros:   make(map[string]db.Distro),
+	}
 	for _, release := range releases {
+		if release.Auxiliary {
+			// Listed separately, since only the first source decides the status of a package
+			r.auxiliary = append(r.auxiliary, release)
+			continue
+		}
 		switch release.Status {
 		case db.StatusUnmatched:
 			r.unmatchedCount++
 			hostname := "N/A"
-			pieces := strings.Split(release.Source, "|")
-			loc := pieces[len(pieces)-1]
-			host, err := url.Parse(loc)
+			host, err := url.Parse(ParseSource(release.Source, "").URL)
 			if err == nil {
 				pieces := strings.Split(host.Hostname(), ".")
 				hostname = pieces[len(pieces)-2]
@@ -160,45 +256,271 @@ func NewReport(releases []db.Release) *Report {
 	return r
 }
 
+// AddStats includes the trends across recorded runs in the Report
+func (r *Report) AddStats(stats *Stats) {
+	r.stats = stats
+}
+
+// AddPackages includes the metadata of the packages in the Report, along with the dependents of
+// every out of date package and the order to update them in
+func (r *Report) AddPackages(packages map[string]db.Package) {
+	r.packages = packages
+	graph := NewGraph(packages)
+	names := make([]string, 0)
+	for _, release := range r.matched {
+		if release.Status != db.StatusOutOfDate {
+			continue
+		}
+		if _, ok := r.impact[release.Package]; ok {
+			continue
+		}
+		r.impact[release.Package] = graph.Impact(release.Package)
+		names = append(names, release.Package)
+	}
+	r.order = graph.Order(names)
+}
+
+// ReportSortOrders are the ways that matched packages may be ordered in a Report
+var ReportSortOrders = map[string]func(r *Report, a, b db.Release) bool{
+	"name": func(r *Report, a, b db.Release) bool {
+		return a.Package < b.Package
+	},
+	"behind": func(r *Report, a, b db.Release) bool {
+		return a.TimeBehind() > b.TimeBehind()
+	},
+	"releases": func(r *Report, a, b db.Release) bool {
+		return a.Behind > b.Behind
+	},
+	"component": func(r *Report, a, b db.Release) bool {
+		ca, cb := r.packages[a.Package].Component, r.packages[b.Package].Component
+		if ca != cb {
+			return ca < cb
+		}
+		return a.Package < b.Package
+	},
+	"dependents": func(r *Report, a, b db.Release) bool {
+		return len(r.impact[a.Package].Transitive) > len(r.impact[b.Package].Transitive)
+	},
+	"score": func(r *Report, a, b db.Release) bool {
+		return a.Score > b.Score
+	},
+}
+
+// AddDistros includes the newest versions shipped by other distributions in the Report
+func (r *Report) AddDistros(distros map[string]db.Distro) {
+	r.distros = distros
+}
+
+// distroCell formats the newest version of a package in other distributions, highlighted if it
+// is newer than the packaged version
+func (r Report) distroCell(release db.Release) (class, text string) {
+	d, ok := r.distros[release.Package]
+	if !ok {
+		return "", ""
+	}
+	// Both come from an outside dump
+	text = fmt.Sprintf(ReportDistro, html.EscapeString(d.Version), html.EscapeString(d.Repo))
+	if release.Current != "" && db.NewVersion(d.Version).CompareStrict(db.NewVersion(release.Current)) < 0 {
+		class = "behind"
+	}
+	return
+}
+
+// SecurityOnly leaves out the matched packages without any known security advisories
+func (r *Report) SecurityOnly() {
+	matched := make([]db.Release, 0)
+	for _, release := range r.matched {
+		if len(release.CVEs) > 0 {
+			matched = append(matched, release)
+		}
+	}
+	r.matched = matched
+}
+
+// Sort orders the matched packages, most neglected, depended on or urgent first unless sorting by name or component.
+// Sorting by component also groups them by component.
+func (r *Report) Sort(by string) error {
+	less, ok := ReportSortOrders[by]
+	if !ok {
+		return fmt.Errorf("unknown sort order '%s'", by)
+	}
+	sort.SliceStable(r.matched, func(i, j int) bool {
+		return less(r, r.matched[i], r.matched[j])
+	})
+	r.byComponent = by == "component"
+	return nil
+}
+
+// statusClass gets the CSS class used to color a Status
+func statusClass(status int) string {
+	switch status {
+	case db.StatusOutOfDate:
+		return "behind"
+	case db.StatusHeldBack:
+		return "held"
+	case db.StatusUpToDate:
+		return "ok"
+	case db.StatusAhead:
+		return "ahead"
+	default:
+		return "failed"
+	}
+}
+
 // Print generates an HTML report
-func (r Report) Print() {
-	fmt.Println(ReportStart)
+func (r Report) Print(w io.Writer) {
+	fmt.Fprint(w, ReportStart)
 	matched := r.outOfDateCount + r.heldBackCount + r.upToDateCount + r.aheadCount
 	behindP := int(math.Floor(float64(r.outOfDateCount) / float64(matched) * 100.0))
 	heldP := int(math.Floor(float64(r.heldBackCount) / float64(matched) * 100.0))
 	okP := int(math.Floor(float64(r.upToDateCount) / float64(matched) * 100.0))
 	aheadP := int(math.Floor(float64(r.aheadCount) / float64(matched) * 100.0))
-	fmt.Printf(ReportSummary, behindP, heldP, okP, aheadP,
+	fmt.Fprintf(w, ReportSummary, behindP, heldP, okP, aheadP,
 		r.outOfDateCount, r.heldBackCount, r.upToDateCount, r.aheadCount,
 		r.unmatchedCount, len(r.failed), matched+r.unmatchedCount+len(r.failed))
-	fmt.Println(ReportMatchHeader)
-	for _, release := range r.matched {
-		var color string
-		switch release.Status {
-		case db.StatusOutOfDate:
-			color = "behind"
-		case db.StatusHeldBack:
-			color = "held"
-		case db.StatusAhead:
-			color = "ahead"
-		default:
-			color = "ok"
+	if r.stats != nil {
+		r.stats.PrintSVG(w)
+	}
+	fmt.Fprint(w, ReportMatchHeader)
+	component := ""
+	for i, release := range r.matched {
+		if p := r.packages[release.Package]; r.byComponent && (i == 0 || p.Component != component) {
+			component = p.Component
+			label := component
+			if label == "" {
+				label = "N/A"
+			}
+			fmt.Fprintf(w, ReportComponentRow, html.EscapeString(label))
+		}
+		behindFor, releasesBehind, dependents, score := "", "", "", ""
+		if release.Status == db.StatusOutOfDate {
+			behindFor = FormatDuration(release.TimeBehind())
+			releasesBehind = strconv.Itoa(release.Behind)
+			score = strconv.FormatFloat(release.Score, 'f', 1, 64)
+			if impact, ok := r.impact[release.Package]; ok {
+				dependents = fmt.Sprintf(ReportDependents, len(impact.Direct), len(impact.Transitive))
+			}
+		}
+		p := r.packages[release.Package]
+		name := release.Package
+		if p.Homepage != "" {
+			name = fmt.Sprintf(ReportHomepageLink, html.EscapeString(p.Homepage), name)
+		}
+		if release.Series != "" {
+			name += " (" + release.Series + ")"
 		}
-		fmt.Printf(ReportMatchRow, release.Package, release.Current, color, release.Latest, release.Source, release.Source)
+		advisories := make([]string, 0)
+		for _, id := range release.CVEs {
+			// Read from a feed on disk, so not trusted either
+			advisories = append(advisories, fmt.Sprintf(ReportAdvisoryLink, html.EscapeString(security.URL(id)),
+				html.EscapeString(id)))
+		}
+		if len(advisories) > 0 {
+			name += ReportSecurityMarker
+		}
+		distroClass, distro := r.distroCell(release)
+		fmt.Fprintf(w, ReportMatchRow, name, p.Component, release.Current, statusClass(release.Status), release.Latest,
+			release.Newer, behindFor, releasesBehind, dependents, score, strings.Join(advisories, " "), distroClass,
+			distro, release.Source, release.Source)
+	}
+	fmt.Fprint(w, ReportTableClose)
+	r.printOrder(w)
+	if len(r.auxiliary) > 0 {
+		fmt.Fprint(w, ReportAuxiliaryHeader)
+		for _, release := range r.auxiliary {
+			fmt.Fprintf(w, ReportAuxiliaryRow, release.Package, release.Current, statusClass(release.Status), release.Latest,
+				release.Source, release.Source)
+		}
+		fmt.Fprint(w, ReportTableClose)
 	}
-	fmt.Println(ReportTableClose)
-	fmt.Println(ReportUnmatchedHeader)
+	r.printLint(w)
+	fmt.Fprint(w, ReportUnmatchedHeader)
 	hosts := make([]string, 0)
 	for host := range r.unmatched {
 		hosts = append(hosts, host)
 	}
 	sort.Strings(hosts)
 	for _, host := range hosts {
-		fmt.Printf(ReportUnmatchedSectionStart, host)
+		fmt.Fprintf(w, ReportUnmatchedSectionStart, host)
 		for _, release := range r.unmatched[host] {
-			fmt.Printf(ReportUnmatchedRow, release.Package, release.Current, release.Source, release.Source)
+			distroClass, distro := r.distroCell(release)
+			fmt.Fprintf(w, ReportUnmatchedRow, release.Package, release.Current, distroClass, distro, release.Source,
+				release.Source)
+		}
+		fmt.Fprint(w, ReportUnmatchedSectionStop)
+	}
+	fmt.Fprint(w, ReportUnmatchedClose)
+}
+
+// printOrder lists the out of date packages in the order they should be updated, if there are any
+func (r Report) printOrder(w io.Writer) {
+	if len(r.order) == 0 {
+		return
+	}
+	latest := make(map[string]string)
+	for _, release := range r.matched {
+		if _, ok := latest[release.Package]; !ok && release.Status == db.StatusOutOfDate {
+			latest[release.Package] = release.Latest
+		}
+	}
+	fmt.Fprint(w, ReportOrderHeader)
+	for i, name := range r.order {
+		impact := r.impact[name]
+		fmt.Fprintf(w, ReportOrderRow, i+1, name, latest[name], len(impact.Direct), len(impact.Transitive))
+	}
+	fmt.Fprint(w, ReportTableClose)
+}
+
+// printLint lists the lint findings of every package, if there are any
+func (r Report) printLint(w io.Writer) {
+	names := make([]string, 0)
+	for name, p := range r.packages {
+		if len(p.Lint) > 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+	fmt.Fprint(w, ReportLintHeader)
+	for _, name := range names {
+		for _, finding := range r.packages[name].Lint {
+			fmt.Fprintf(w, ReportLintRow, name, html.EscapeString(finding))
 		}
-		fmt.Println(ReportUnmatchedSectionStop)
 	}
-	fmt.Println(ReportUnmatchedClose)
+	fmt.Fprint(w, ReportTableClose)
+}
+
+// PrintJSON generates a JSON report
+func (r Report) PrintJSON(w io.Writer) error {
+	matched := r.outOfDateCount + r.heldBackCount + r.upToDateCount + r.aheadCount
+	out := jsonReport{
+		Summary: map[string]int{
+			"out_of_date": r.outOfDateCount,
+			"held_back":   r.heldBackCount,
+			"up_to_date":  r.upToDateCount,
+			"ahead":       r.aheadCount,
+			"unmatched":   r.unmatchedCount,
+			"failed":      len(r.failed),
+			"total":       matched + r.unmatchedCount + len(r.failed),
+		},
+		Matched:   r.matched,
+		Unmatched: r.unmatched,
+		Failed:    r.failed,
+		Auxiliary: r.auxiliary,
+		Stats:     r.stats,
+		Packages:  r.packages,
+		Impact:    r.impact,
+		Order:     r.order,
+		Distros:   r.distros,
+	}
+	return printJSON(w, out)
+}
+
+// printJSON writes out any value as indented JSON
+func printJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(v)
 }
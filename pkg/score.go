@@ -0,0 +1,69 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package pkg
+
+import (
+	"github.com/DataDrake/ypkg-update-checker/config"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"math"
+	"time"
+)
+
+// Magnitude gets how big the change between two versions is: 3 when the first number differs,
+// 2 for the second and 1 for anything else
+func Magnitude(current, latest string) int {
+	a, b := db.NewVersion(current), db.NewVersion(latest)
+	for i := 0; i < len(a) && i < len(b) && i < 2; i++ {
+		if a[i] != b[i] {
+			return 3 - i
+		}
+	}
+	return 1
+}
+
+// Score rates how urgently an out of date Release should be updated, or 0 if it is not out of date
+// or an auxiliary source
+func Score(r db.Release, dependents int, w config.Weights) float64 {
+	if r.Status != db.StatusOutOfDate || r.Auxiliary {
+		return 0
+	}
+	weeks := float64(r.TimeBehind()) / float64(7*24*time.Hour)
+	score := w.Magnitude*float64(Magnitude(r.Current, r.Latest)) +
+		w.Age*weeks +
+		w.Dependents*math.Log2(float64(1+dependents)) +
+		w.Security*float64(r.Advisories)
+	return math.Round(score*10) / 10
+}
+
+// Rescore sets the Score of every Release, counting the dependents of each package with their metadata
+func Rescore(releases []db.Release, packages map[string]db.Package, w config.Weights) []db.Release {
+	graph := NewGraph(packages)
+	dependents := make(map[string]int)
+	for i, r := range releases {
+		if r.Status != db.StatusOutOfDate || r.Auxiliary {
+			releases[i].Score = 0
+			continue
+		}
+		count, ok := dependents[r.Package]
+		if !ok {
+			count = len(graph.Impact(r.Package).Transitive)
+			dependents[r.Package] = count
+		}
+		releases[i].Score = Score(r, count, w)
+	}
+	return releases
+}
@@ -0,0 +1,123 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package pkg
+
+import (
+	"fmt"
+	"github.com/DataDrake/ypkg-update-checker/github"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	// SourceTarball is an archive, checked against its sha256sum
+	SourceTarball = "tarball"
+	// SourceGit is a git repository, checked out at a tag or commit
+	SourceGit = "git"
+)
+
+// GitPrefix marks the location of a git repository in package.yml
+const GitPrefix = "git|"
+
+// archiveRegex matches the extension of an archive, to be removed before looking for a version
+var archiveRegex = regexp.MustCompile(`(\.tar)?\.(gz|bz2|xz|lz|lzma|zst|tgz|tbz2|txz|zip|gem|crate)$`)
+
+// versionRegex matches a version in the name of an archive or a tag, e.g. "foo-1.2.3", "foo_1.2b1" or "v1.2"
+var versionRegex = regexp.MustCompile(`(?:^|[-_/])[vV]?([0-9]+(?:\.[0-9]+)*(?:[-._]?(?:alpha|beta|pre|rc|a|b|p)[0-9]*)?)(?:$|[-_.])`)
+
+// URLVersion finds the version in the last part of a URL or a git tag, returning "" if there is none
+func URLVersion(location string) string {
+	name := archiveRegex.ReplaceAllString(path.Base(location), "")
+	found := versionRegex.FindStringSubmatch(name)
+	if found == nil {
+		return ""
+	}
+	return found[1]
+}
+
+// Source is a single entry in the "source" section of a package.yml, e.g.:
+//
+//	source:
+//	    - https://example.com/foo-1.2.3.tar.xz : <sha256sum>
+//	    - git|https://github.com/foo/bar.git : v1.2.3
+type Source struct {
+	Kind string
+	URL  string
+	// SHA256 is the checksum of a tarball
+	SHA256 string
+	// Ref is the tag or commit of a git repository
+	Ref string
+}
+
+// ParseSource interprets both halves of a source entry
+func ParseSource(location, value string) Source {
+	if strings.HasPrefix(location, GitPrefix) {
+		return Source{
+			Kind: SourceGit,
+			URL:  strings.TrimPrefix(location, GitPrefix),
+			Ref:  value,
+		}
+	}
+	return Source{
+		Kind:   SourceTarball,
+		URL:    location,
+		SHA256: value,
+	}
+}
+
+// UnmarshalYAML reads a Source from its single entry mapping
+func (s *Source) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var entry map[string]string
+	if err := unmarshal(&entry); err != nil {
+		return err
+	}
+	if len(entry) != 1 {
+		return fmt.Errorf("source must have exactly one location, found %d", len(entry))
+	}
+	for location, value := range entry {
+		*s = ParseSource(location, value)
+	}
+	return nil
+}
+
+// Location gets the key of the source entry, as written in package.yml
+func (s Source) Location() string {
+	if s.Kind == SourceGit {
+		return GitPrefix + s.URL
+	}
+	return s.URL
+}
+
+// Value gets the value of the source entry, as written in package.yml
+func (s Source) Value() string {
+	if s.Kind == SourceGit {
+		return s.Ref
+	}
+	return s.SHA256
+}
+
+// Version finds the version of the Source from its URL or tag, returning "" for commits and unversioned URLs
+func (s Source) Version() string {
+	if s.Kind == SourceGit {
+		if github.IsCommit(s.Ref) {
+			return ""
+		}
+		return URLVersion(s.Ref)
+	}
+	return URLVersion(s.URL)
+}
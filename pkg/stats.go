@@ -0,0 +1,187 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package pkg
+
+import (
+	"fmt"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StatsHeader is the header for the trends section of a report
+const StatsHeader = `
+<h1 id="trends">Trends</h1>
+`
+
+// StatsChartStart is the format string for the beginning of a single chart
+const StatsChartStart = `
+<h3>%s</h3>
+<svg width="%d" height="%d" viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">
+<line x1="0" y1="%d" x2="%d" y2="%d" stroke="#EEE"/>
+<text x="4" y="12" fill="#EEE" font-size="10">%s</text>
+`
+
+// StatsChartLine is the format string for a single series in a chart
+const StatsChartLine = "<polyline fill=\"none\" stroke=\"%s\" stroke-width=\"2\" points=\"%s\"><title>%s</title></polyline>\n"
+
+// StatsChartLegend is the format string for the label of a single series in a chart
+const StatsChartLegend = "<text x=\"%d\" y=\"%d\" fill=\"%s\" font-size=\"10\">%s</text>\n"
+
+// StatsChartClose terminates a chart
+const StatsChartClose = "</svg>\n"
+
+// StatsChartWidth is the width of every chart in pixels
+const StatsChartWidth = 600
+
+// StatsChartHeight is the height of every chart in pixels
+const StatsChartHeight = 160
+
+// statsColors are used in turn for each series of a chart
+var statsColors = []string{"#F00", "#FF0", "#0F0", "#0EF", "#F0F", "#F93", "#99F", "#FFF"}
+
+// StatsPoint is the state of the repository at the end of a single run
+type StatsPoint struct {
+	Run        int                `json:"run"`
+	Time       time.Time          `json:"time"`
+	Sources    int                `json:"sources"`
+	OutOfDate  int                `json:"out_of_date"`
+	Unmatched  int                `json:"unmatched"`
+	Behind     float64            `json:"median_days_behind"`
+	MatchRates map[string]float64 `json:"match_rates"`
+}
+
+// Stats is a series of StatsPoints, one for each recorded run
+type Stats struct {
+	Points    []StatsPoint `json:"points"`
+	Providers []string     `json:"providers"`
+}
+
+// NewStats builds a series from the runs and the provider summaries of each run
+func NewStats(runs []*db.Run, providers map[int][]db.RunProvider) *Stats {
+	s := &Stats{
+		Points: make([]StatsPoint, 0),
+	}
+	seen := make(map[string]bool)
+	for _, run := range runs {
+		if run.Sources == 0 {
+			continue
+		}
+		point := StatsPoint{
+			Run:        run.ID,
+			Time:       run.Finished,
+			Sources:    run.Sources,
+			OutOfDate:  run.OutOfDate,
+			Unmatched:  run.Unmatched,
+			Behind:     run.Behind.Hours() / 24,
+			MatchRates: make(map[string]float64),
+		}
+		for _, p := range providers[run.ID] {
+			point.MatchRates[p.Provider] = float64(p.Matches) / float64(run.Sources) * 100.0
+			if !seen[p.Provider] {
+				seen[p.Provider] = true
+				s.Providers = append(s.Providers, p.Provider)
+			}
+		}
+		s.Points = append(s.Points, point)
+	}
+	sort.Strings(s.Providers)
+	return s
+}
+
+// Print writes out the series as a plain-text table
+func (s Stats) Print() {
+	if len(s.Points) == 0 {
+		fmt.Println("No runs recorded.")
+		return
+	}
+	fmt.Printf("%5s  %-16s  %11s  %10s  %14s", "Run", "Finished", "Out of Date", "Unmatched", "Median Behind")
+	for _, p := range s.Providers {
+		fmt.Printf("  %10s", p)
+	}
+	fmt.Println()
+	for _, point := range s.Points {
+		fmt.Printf("%5d  %-16s  %11d  %10d  %13.1fd", point.Run, point.Time.Format(HistoryTimeFormat),
+			point.OutOfDate, point.Unmatched, point.Behind)
+		for _, p := range s.Providers {
+			fmt.Printf("  %9.1f%%", point.MatchRates[p])
+		}
+		fmt.Println()
+	}
+}
+
+// PrintSVG generates an HTML section with inline SVG charts of the series
+func (s Stats) PrintSVG(w io.Writer) {
+	if len(s.Points) == 0 {
+		return
+	}
+	fmt.Fprint(w, StatsHeader)
+	outOfDate := make([]float64, len(s.Points))
+	unmatched := make([]float64, len(s.Points))
+	behind := make([]float64, len(s.Points))
+	for i, point := range s.Points {
+		outOfDate[i] = float64(point.OutOfDate)
+		unmatched[i] = float64(point.Unmatched)
+		behind[i] = point.Behind
+	}
+	printChart(w, "Out of Date and Unmatched Sources", []string{"Out of Date", "Unmatched"}, outOfDate, unmatched)
+	printChart(w, "Median Days Behind Upstream", []string{"Median Days Behind"}, behind)
+	rates := make([][]float64, 0)
+	for _, p := range s.Providers {
+		rate := make([]float64, len(s.Points))
+		for i, point := range s.Points {
+			rate[i] = point.MatchRates[p]
+		}
+		rates = append(rates, rate)
+	}
+	printChart(w, "Match Rate by Provider (%)", s.Providers, rates...)
+}
+
+// printChart draws one or more series as lines, scaled to the largest value of any of them
+func printChart(w io.Writer, title string, names []string, series ...[]float64) {
+	max := 0.0
+	for _, values := range series {
+		for _, v := range values {
+			if v > max {
+				max = v
+			}
+		}
+	}
+	if max == 0 {
+		max = 1
+	}
+	height := StatsChartHeight - 20
+	fmt.Fprintf(w, StatsChartStart, title, StatsChartWidth, StatsChartHeight, StatsChartWidth, StatsChartHeight,
+		height, StatsChartWidth, height, fmt.Sprintf("max: %.1f", max))
+	for i, values := range series {
+		points := make([]string, len(values))
+		for j, v := range values {
+			x := 0
+			if len(values) > 1 {
+				x = j * StatsChartWidth / (len(values) - 1)
+			}
+			y := height - int(v/max*float64(height-20))
+			points[j] = fmt.Sprintf("%d,%d", x, y)
+		}
+		color := statsColors[i%len(statsColors)]
+		fmt.Fprintf(w, StatsChartLine, color, strings.Join(points, " "), names[i])
+		fmt.Fprintf(w, StatsChartLegend, 4+i*100, StatsChartHeight-4, color, names[i])
+	}
+	fmt.Fprint(w, StatsChartClose)
+}
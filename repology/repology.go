@@ -0,0 +1,107 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package repology
+
+import (
+	"encoding/json"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"io/ioutil"
+	"sort"
+)
+
+// usable are the statuses of the packages worth comparing with, leaving out development
+// versions and anything Repology could not make sense of
+var usable = map[string]bool{
+	"newest":   true,
+	"unique":   true,
+	"outdated": true,
+	"legacy":   true,
+}
+
+// Entry is a single package of a project, as shipped by one repository
+type Entry struct {
+	Repo        string `json:"repo"`
+	SrcName     string `json:"srcname"`
+	BinName     string `json:"binname"`
+	VisibleName string `json:"visiblename"`
+	Version     string `json:"version"`
+	Status      string `json:"status"`
+}
+
+// Dump is every project of a Repology dump, along with an index of the names used by each repository
+type Dump struct {
+	projects map[string][]Entry
+	names    map[string][]string
+}
+
+// Load reads a dump in the layout of the Repology API, an object of projects with a list of entries each
+func Load(path string) (*Dump, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d := &Dump{
+		projects: make(map[string][]Entry),
+		names:    make(map[string][]string),
+	}
+	if err = json.Unmarshal(raw, &d.projects); err != nil {
+		return nil, err
+	}
+	for project, entries := range d.projects {
+		seen := make(map[string]bool)
+		for _, e := range entries {
+			for _, name := range []string{e.SrcName, e.BinName} {
+				if name != "" && name != project && !seen[name] {
+					seen[name] = true
+					d.names[name] = append(d.names[name], project)
+				}
+			}
+		}
+	}
+	for _, projects := range d.names {
+		sort.Strings(projects)
+	}
+	return d, nil
+}
+
+// Best finds the newest version of a package in any repository that is not excluded, looking it up
+// by project, or else by the name of the package in any repository
+func (d *Dump) Best(name, project string, exclude []string) (version, repo string) {
+	excluded := make(map[string]bool)
+	for _, r := range exclude {
+		excluded[r] = true
+	}
+	projects := []string{name}
+	if project != "" {
+		projects = []string{project}
+	} else if _, ok := d.projects[name]; !ok {
+		projects = d.names[name]
+	}
+	var best db.Version
+	for _, p := range projects {
+		for _, e := range d.projects[p] {
+			if excluded[e.Repo] || !usable[e.Status] || e.Version == "" {
+				continue
+			}
+			v := db.NewVersion(e.Version)
+			if best == nil || v.CompareStrict(best) < 0 {
+				best, version, repo = v, e.Version, e.Repo
+			}
+		}
+	}
+	return
+}
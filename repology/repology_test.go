@@ -0,0 +1,67 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package repology
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const dump = `{
+"foo": [
+    {"repo": "arch", "srcname": "foo", "version": "1.2", "status": "outdated"},
+    {"repo": "fedora", "srcname": "foo", "version": "1.2.1", "status": "newest"},
+    {"repo": "debian", "srcname": "foo", "version": "1.3rc1", "status": "devel"},
+    {"repo": "solus", "srcname": "foo", "version": "9.0", "status": "newest"}
+],
+"libbar": [
+    {"repo": "alpine", "srcname": "bar", "binname": "bar", "version": "0.3", "status": "newest"}
+]
+}`
+
+func TestBest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repology")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dump.json")
+	if err = ioutil.WriteFile(path, []byte(dump), 0644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load dump: %s", err)
+	}
+	tests := []struct {
+		name, project string
+		version, repo string
+	}{
+		{"foo", "", "1.2.1", "fedora"},
+		{"bar", "", "0.3", "alpine"},
+		{"baz", "libbar", "0.3", "alpine"},
+		{"baz", "", "", ""},
+	}
+	for _, test := range tests {
+		version, repo := d.Best(test.name, test.project, []string{"solus"})
+		if version != test.version || repo != test.repo {
+			t.Errorf("Expected %s from %s for '%s', found %s from %s", test.version, test.repo, test.name, version, repo)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package security
+
+import (
+	"encoding/json"
+	"github.com/DataDrake/ypkg-update-checker/db"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Range is a span of vulnerable versions, where any empty bound is left open
+type Range struct {
+	Exact          string
+	StartIncluding string
+	StartExcluding string
+	EndIncluding   string
+	EndExcluding   string
+}
+
+// compare orders two versions, returning a positive number if a is newer than b, where every piece
+// counts (e.g. "1.1" is older than "1.1.1")
+func compare(a, b string) int {
+	return db.NewVersion(b).CompareStrict(db.NewVersion(a))
+}
+
+// Contains checks if a version falls within the Range
+func (r Range) Contains(version string) bool {
+	if r.Exact != "" {
+		return compare(version, r.Exact) == 0
+	}
+	if r.StartIncluding != "" && compare(version, r.StartIncluding) < 0 {
+		return false
+	}
+	if r.StartExcluding != "" && compare(version, r.StartExcluding) <= 0 {
+		return false
+	}
+	if r.EndIncluding != "" && compare(version, r.EndIncluding) > 0 {
+		return false
+	}
+	if r.EndExcluding != "" && compare(version, r.EndExcluding) >= 0 {
+		return false
+	}
+	return true
+}
+
+// Feed is every advisory of a vulnerability feed, indexed by the product affected
+type Feed struct {
+	ranges map[string]map[string][]Range
+}
+
+// add records a vulnerable Range of a product for an advisory
+func (f *Feed) add(product, id string, r Range) {
+	product = strings.ToLower(product)
+	if f.ranges[product] == nil {
+		f.ranges[product] = make(map[string][]Range)
+	}
+	f.ranges[product][id] = append(f.ranges[product][id], r)
+}
+
+// Load reads an NVD JSON feed or a JSON list of OSV entries
+func Load(path string) (*Feed, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &Feed{
+		ranges: make(map[string]map[string][]Range),
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+		err = f.loadOSV(raw)
+	} else {
+		err = f.loadNVD(raw)
+	}
+	return f, err
+}
+
+// Match finds the IDs of every advisory affecting a version of a product
+func (f *Feed) Match(product, version string) []string {
+	ids := make([]string, 0)
+	if f == nil || product == "" || version == "" {
+		return ids
+	}
+	for id, ranges := range f.ranges[strings.ToLower(product)] {
+		for _, r := range ranges {
+			if r.Contains(version) {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// URL gets the page describing an advisory, from NVD for CVEs and OSV for the rest
+func URL(id string) string {
+	if strings.HasPrefix(id, "CVE-") {
+		return "https://nvd.nist.gov/vuln/detail/" + id
+	}
+	return "https://osv.dev/vulnerability/" + id
+}
+
+// nvdNode is a single node of the configurations of an NVD entry, which may be nested
+type nvdNode struct {
+	Children []nvdNode `json:"children"`
+	Matches  []struct {
+		Vulnerable     bool   `json:"vulnerable"`
+		CPE            string `json:"cpe23Uri"`
+		StartIncluding string `json:"versionStartIncluding"`
+		StartExcluding string `json:"versionStartExcluding"`
+		EndIncluding   string `json:"versionEndIncluding"`
+		EndExcluding   string `json:"versionEndExcluding"`
+	} `json:"cpe_match"`
+}
+
+// loadNVD reads the entries of an NVD JSON 1.1 feed, keyed by the "vendor:product" of their CPEs
+func (f *Feed) loadNVD(raw []byte) error {
+	var feed struct {
+		Items []struct {
+			CVE struct {
+				Meta struct {
+					ID string `json:"ID"`
+				} `json:"CVE_data_meta"`
+			} `json:"cve"`
+			Configurations struct {
+				Nodes []nvdNode `json:"nodes"`
+			} `json:"configurations"`
+		} `json:"CVE_Items"`
+	}
+	if err := json.Unmarshal(raw, &feed); err != nil {
+		return err
+	}
+	for _, item := range feed.Items {
+		nodes := item.Configurations.Nodes
+		for len(nodes) > 0 {
+			node := nodes[0]
+			nodes = append(nodes[1:], node.Children...)
+			for _, m := range node.Matches {
+				// cpe:2.3:part:vendor:product:version:...
+				pieces := strings.Split(m.CPE, ":")
+				if !m.Vulnerable || len(pieces) < 6 {
+					continue
+				}
+				r := Range{
+					StartIncluding: m.StartIncluding,
+					StartExcluding: m.StartExcluding,
+					EndIncluding:   m.EndIncluding,
+					EndExcluding:   m.EndExcluding,
+				}
+				if pieces[5] != "*" && pieces[5] != "-" {
+					r.Exact = pieces[5]
+				}
+				f.add(pieces[3]+":"+pieces[4], item.CVE.Meta.ID, r)
+			}
+		}
+	}
+	return nil
+}
+
+// loadOSV reads a list of OSV entries, keyed by the name of the affected package
+func (f *Feed) loadOSV(raw []byte) error {
+	var entries []struct {
+		ID       string `json:"id"`
+		Affected []struct {
+			Package struct {
+				Name string `json:"name"`
+			} `json:"package"`
+			Ranges []struct {
+				Type   string `json:"type"`
+				Events []struct {
+					Introduced   string `json:"introduced"`
+					Fixed        string `json:"fixed"`
+					LastAffected string `json:"last_affected"`
+				} `json:"events"`
+			} `json:"ranges"`
+			Versions []string `json:"versions"`
+		} `json:"affected"`
+	}
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		for _, affected := range entry.Affected {
+			name := affected.Package.Name
+			for _, version := range affected.Versions {
+				f.add(name, entry.ID, Range{Exact: version})
+			}
+			for _, ranges := range affected.Ranges {
+				// Commit hashes cannot be compared with versions
+				if ranges.Type == "GIT" {
+					continue
+				}
+				var r *Range
+				for _, event := range ranges.Events {
+					switch {
+					case event.Introduced != "":
+						r = &Range{}
+						if event.Introduced != "0" {
+							r.StartIncluding = event.Introduced
+						}
+					case r == nil:
+						continue
+					case event.Fixed != "":
+						r.EndExcluding = event.Fixed
+						f.add(name, entry.ID, *r)
+						r = nil
+					case event.LastAffected != "":
+						r.EndIncluding = event.LastAffected
+						f.add(name, entry.ID, *r)
+						r = nil
+					}
+				}
+				if r != nil {
+					// Not fixed yet
+					f.add(name, entry.ID, *r)
+				}
+			}
+		}
+	}
+	return nil
+}
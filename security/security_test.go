@@ -0,0 +1,139 @@
+//
+// Copyright 2018 Bryan T. Meyers <bmeyers@datadrake.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package security
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRangeContains(t *testing.T) {
+	tests := []struct {
+		r        Range
+		version  string
+		contains bool
+	}{
+		{Range{Exact: "1.0"}, "1.0", true},
+		{Range{Exact: "1.0"}, "1.0.2", false},
+		{Range{Exact: "1.0.2"}, "1.0", false},
+		{Range{EndExcluding: "1.1.1"}, "1.1", true},
+		{Range{EndExcluding: "1.1.1"}, "1.1.1", false},
+		{Range{EndExcluding: "1.1.1"}, "1.1.2", false},
+		{Range{EndIncluding: "1.1"}, "1.1", true},
+		{Range{EndIncluding: "1.1"}, "1.1.1", false},
+		{Range{StartIncluding: "2.4.1"}, "2.4", false},
+		{Range{StartIncluding: "2.4.1"}, "2.4.1", true},
+		{Range{StartExcluding: "2.4"}, "2.4", false},
+		{Range{StartExcluding: "2.4"}, "2.4.1", true},
+		{Range{StartIncluding: "1.0", EndExcluding: "1.2.4"}, "1.2.3", true},
+		{Range{StartIncluding: "1.0", EndExcluding: "1.2.4"}, "0.9", false},
+		{Range{}, "3.0", true},
+	}
+	for _, test := range tests {
+		if test.r.Contains(test.version) != test.contains {
+			t.Errorf("Expected %+v to contain '%s': %t", test.r, test.version, test.contains)
+		}
+	}
+}
+
+// loadFeed writes a feed to a temporary file and loads it
+func loadFeed(t *testing.T, feed string) *Feed {
+	dir, err := ioutil.TempDir("", "security")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "feed.json")
+	if err = ioutil.WriteFile(path, []byte(feed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load feed: %s", err)
+	}
+	return f
+}
+
+const nvdFeed = `{"CVE_Items": [
+{"cve": {"CVE_data_meta": {"ID": "CVE-2018-0001"}}, "configurations": {"nodes": [{"cpe_match": [
+    {"vulnerable": true, "cpe23Uri": "cpe:2.3:a:foo:foo:*:*:*:*:*:*:*:*", "versionStartIncluding": "1.0", "versionEndExcluding": "1.1.1"}
+]}]}},
+{"cve": {"CVE_data_meta": {"ID": "CVE-2018-0002"}}, "configurations": {"nodes": [{"children": [{"cpe_match": [
+    {"vulnerable": true, "cpe23Uri": "cpe:2.3:a:foo:foo:1.0:*:*:*:*:*:*:*"}
+]}]}]}},
+{"cve": {"CVE_data_meta": {"ID": "CVE-2018-0003"}}, "configurations": {"nodes": [{"cpe_match": [
+    {"vulnerable": false, "cpe23Uri": "cpe:2.3:a:foo:foo:*:*:*:*:*:*:*:*"},
+    {"vulnerable": true, "cpe23Uri": "cpe:2.3:a:foo:foo:*:*:*:*:*:*:*:*", "versionStartExcluding": "2.4", "versionEndIncluding": "2.5"}
+]}]}}
+]}`
+
+func TestMatchNVD(t *testing.T) {
+	f := loadFeed(t, nvdFeed)
+	tests := map[string]string{
+		"0.9":   "",
+		"1.0":   "CVE-2018-0001,CVE-2018-0002",
+		"1.0.2": "CVE-2018-0001",
+		"1.1":   "CVE-2018-0001",
+		"1.1.1": "",
+		"2.4":   "",
+		"2.4.1": "CVE-2018-0003",
+		"2.5":   "CVE-2018-0003",
+		"2.5.1": "",
+	}
+	for version, expected := range tests {
+		if found := strings.Join(f.Match("FOO:foo", version), ","); found != expected {
+			t.Errorf("Expected '%s' for foo %s, found '%s'", expected, version, found)
+		}
+	}
+	if len(f.Match("bar:bar", "1.0")) != 0 {
+		t.Error("Expected no advisories for an unknown product")
+	}
+}
+
+const osvFeed = `[
+{"id": "OSV-2018-1", "affected": [{"package": {"name": "foo"}, "ranges": [
+    {"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.1.1"}]},
+    {"type": "ECOSYSTEM", "events": [{"introduced": "2.4.1"}, {"last_affected": "2.5"}]},
+    {"type": "GIT", "events": [{"introduced": "0"}, {"fixed": "abcdef0"}]}
+]}]},
+{"id": "OSV-2018-2", "affected": [{"package": {"name": "foo"}, "ranges": [
+    {"type": "SEMVER", "events": [{"introduced": "3.0"}]}
+], "versions": ["1.0"]}]}
+]`
+
+func TestMatchOSV(t *testing.T) {
+	f := loadFeed(t, osvFeed)
+	tests := map[string]string{
+		"0.1":   "OSV-2018-1",
+		"1.0":   "OSV-2018-1,OSV-2018-2",
+		"1.1":   "OSV-2018-1",
+		"1.1.1": "",
+		"2.4":   "",
+		"2.4.1": "OSV-2018-1",
+		"2.5":   "OSV-2018-1",
+		"2.5.1": "",
+		"3.2":   "OSV-2018-2",
+	}
+	for version, expected := range tests {
+		if found := strings.Join(f.Match("foo", version), ","); found != expected {
+			t.Errorf("Expected '%s' for foo %s, found '%s'", expected, version, found)
+		}
+	}
+}